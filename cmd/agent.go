@@ -0,0 +1,84 @@
+/*
+Copyright © 2025 Ryan Nemeth
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"os"
+
+	"github.com/rnemeth90/yahba/internal/agent"
+	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+var (
+	agentListen   string
+	agentToken    string
+	agentTLSCert  string
+	agentTLSKey   string
+	agentLogLevel string
+)
+
+// agentCmd represents the agent command
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Run a load generation agent for a distributed test",
+	Long: `Run a load generation agent that accepts tests from a coordinator.
+
+Start one agent per load generating host, then point a coordinator at them.
+Agents only accept tests from coordinators that present the same token:
+
+  export YAHBA_AGENT_TOKEN=$(openssl rand -hex 16)
+  yahba agent --listen 0.0.0.0:7000 --tls-cert agent.pem --tls-key agent-key.pem
+  yahba run --url http://example.com --requests 1000 --rps 100 --agents https://host1:7000,https://host2:7000
+
+The coordinator sends the whole test config to its agents, so it refuses to
+send credentials (auth passwords and tokens, proxy credentials, cookies) to
+agents that are not served over https. Agents never read files, environment
+variables or sockets on a coordinator's behalf: tests using --cert, --key,
+--cacert, --unix-socket or request signing are rejected. Cookies from
+--cookie-file are read by the coordinator and sent along with the test.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if agentToken == "" {
+			agentToken = os.Getenv(agent.TokenEnv)
+		}
+
+		a := &agent.Agent{
+			Listen:      agentListen,
+			Token:       agentToken,
+			TLSCertFile: agentTLSCert,
+			TLSKeyFile:  agentTLSKey,
+			Logger:      logger.New(agentLogLevel, "stdout", false),
+		}
+		if err := a.Run(); err != nil {
+			a.Logger.Error("Agent encountered a critical error: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+	agentCmd.PersistentFlags().StringVar(&agentListen, "listen", "127.0.0.1:7000", "Address to listen for coordinator connections on")
+	agentCmd.PersistentFlags().StringVar(&agentToken, "token", "", "Token coordinators must present (default: $YAHBA_AGENT_TOKEN)")
+	agentCmd.PersistentFlags().StringVar(&agentTLSCert, "tls-cert", "", "Certificate file to serve https with (PEM)")
+	agentCmd.PersistentFlags().StringVar(&agentTLSKey, "tls-key", "", "Private key file to serve https with (PEM)")
+	agentCmd.PersistentFlags().StringVarP(&agentLogLevel, "log-level", "l", "info", "Logging level (debug, info, warn, error)")
+}
@@ -28,10 +28,14 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/rnemeth90/yahba/internal/agent"
+	"github.com/rnemeth90/yahba/internal/client"
 	"github.com/rnemeth90/yahba/internal/config"
 	"github.com/rnemeth90/yahba/internal/logger"
 	"github.com/rnemeth90/yahba/internal/report"
+	"github.com/rnemeth90/yahba/internal/server"
 	"github.com/rnemeth90/yahba/internal/util"
 	"github.com/rnemeth90/yahba/internal/worker"
 	"github.com/spf13/cobra"
@@ -74,9 +78,10 @@ func init() {
 	runCmd.PersistentFlags().IntVar(&c.RPS, "rps", 1, "Requests per second")
 	runCmd.PersistentFlags().BoolVarP(&c.Insecure, "insecure", "i", false, "Disable SSL/TLS verification")
 	runCmd.PersistentFlags().StringVar(&c.Resolver, "resolver", "", "Custom DNS resolver (IP:Port)")
-	runCmd.PersistentFlags().StringVarP(&c.Proxy, "proxy", "P", "", "Proxy server (IP:Port)")
+	runCmd.PersistentFlags().StringVarP(&c.Proxy, "proxy", "P", "", "Proxy URL (http://, https://, socks5:// or socks5h://host:port)")
 	runCmd.PersistentFlags().BoolVarP(&c.KeepAlive, "keep-alive", "k", false, "Enable HTTP keep-alive")
-	runCmd.PersistentFlags().BoolVar(&c.HTTP2, "http2", false, "Enable HTTP/2 support")
+	runCmd.PersistentFlags().BoolVar(&c.HTTP2, "http2", false, "Enable HTTP/2 support (h2c with prior knowledge for http:// URLs)")
+	runCmd.PersistentFlags().BoolVar(&c.HTTP3, "http3", false, "Use HTTP/3 over QUIC (https only)")
 	runCmd.PersistentFlags().StringVarP(&c.LogLevel, "log-level", "l", "error", "Logging level (debug, info, warn, error)")
 	runCmd.PersistentFlags().BoolVar(&c.Compression, "compression", false, "Enable HTTP compression (gzip)")
 	runCmd.PersistentFlags().StringVar(&c.ProxyUser, "proxy-user", "", "Proxy authentication username")
@@ -88,6 +93,51 @@ func init() {
 	runCmd.PersistentFlags().StringVar(&c.FileName, "filename", "", "Specify a file name when using --out file")
 	runCmd.PersistentFlags().BoolVar(&c.Server, "server", false, "Start a test server")
 	runCmd.PersistentFlags().BoolVarP(&c.ReuseConnections, "reuse-connections", "R", false, "Multiplex connections, only works with HTTP2")
+	runCmd.PersistentFlags().IntVar(&c.MaxConnsPerHost, "max-conns-per-host", 0, "Maximum connections to the target, including those in use (0 means no limit)")
+	runCmd.PersistentFlags().IntVar(&c.MaxIdleConns, "max-idle-conns", 0, "Maximum idle connections kept for reuse (0 uses the default of 500)")
+	runCmd.PersistentFlags().DurationVar(&c.IdleConnTimeout, "idle-conn-timeout", 0, "How long idle connections are kept (defaults to the request timeout)")
+	runCmd.PersistentFlags().BoolVar(&c.NewConnPerRequest, "new-conn-per-request", false, "Open a new connection for every request")
+	runCmd.PersistentFlags().StringVar(&c.SourceAddrs, "source-addr", "", "Local IPs or CIDR ranges to bind connections to (10.0.0.5,10.0.1.0/29)")
+	runCmd.PersistentFlags().StringVar(&c.UnixSocket, "unix-socket", "", "Send requests over this Unix socket, keeping the host and path from --url (or use --url unix:///path.sock:/path)")
+	runCmd.PersistentFlags().BoolVar(&c.Sessions, "sessions", false, "Give every worker its own cookie jar so sessions persist across its requests")
+	runCmd.PersistentFlags().StringVar(&c.CookieFile, "cookie-file", "", "Seed every worker's cookie jar from a Netscape format cookie file (implies --sessions)")
+	runCmd.PersistentFlags().StringVar(&c.Auth, "auth", "", "Authentication scheme (basic, digest, bearer, oauth2)")
+	runCmd.PersistentFlags().StringVar(&c.AuthUser, "auth-user", "", "Username for basic and digest authentication")
+	runCmd.PersistentFlags().StringVar(&c.AuthPassword, "auth-password", "", "Password for basic and digest authentication")
+	runCmd.PersistentFlags().StringVar(&c.AuthToken, "auth-token", "", "Static bearer token")
+	runCmd.PersistentFlags().StringVar(&c.OAuth2TokenURL, "oauth2-token-url", "", "OAuth2 token endpoint for the client credentials grant")
+	runCmd.PersistentFlags().StringVar(&c.OAuth2ClientID, "oauth2-client-id", "", "OAuth2 client ID")
+	runCmd.PersistentFlags().StringVar(&c.OAuth2ClientSecret, "oauth2-client-secret", "", "OAuth2 client secret")
+	runCmd.PersistentFlags().StringVar(&c.OAuth2Scopes, "oauth2-scopes", "", "Comma-separated OAuth2 scopes to request")
+	runCmd.PersistentFlags().StringVar(&c.Sign, "sign", "", "Request signing algorithm (hmac-sha256, hmac-sha512, aws-sigv4)")
+	runCmd.PersistentFlags().StringVar(&c.SignSecretEnv, "sign-secret-env", "", "Environment variable holding the HMAC signing secret")
+	runCmd.PersistentFlags().StringVar(&c.SignSecretFile, "sign-secret-file", "", "File holding the HMAC signing secret")
+	runCmd.PersistentFlags().StringVar(&c.SignHeader, "sign-header", "X-Signature", "Header carrying the HMAC signature")
+	runCmd.PersistentFlags().StringVar(&c.SignTimestampHeader, "sign-timestamp-header", "X-Timestamp", "Header carrying the HMAC signing timestamp")
+	runCmd.PersistentFlags().StringVar(&c.AWSRegion, "aws-region", "", "AWS region for SigV4 signing")
+	runCmd.PersistentFlags().StringVar(&c.AWSService, "aws-service", "", "AWS service for SigV4 signing (e.g. execute-api)")
+	runCmd.PersistentFlags().StringVar(&c.AWSProfile, "aws-profile", "", "Profile in the AWS shared credentials file")
+	runCmd.PersistentFlags().StringVar(&c.AWSCredentialsFile, "aws-credentials-file", "", "AWS shared credentials file (default ~/.aws/credentials)")
+	runCmd.PersistentFlags().StringVar(&c.SourceAddrMode, "source-addr-mode", "round-robin", "How each new connection picks a source address (round-robin, random)")
+	runCmd.PersistentFlags().BoolVar(&c.SpreadAddrs, "spread-addrs", false, "Resolve every address of the target and spread connections evenly across them")
+	runCmd.PersistentFlags().StringArrayVar(&c.Resolve, "resolve", nil, "Connect to addr instead of resolving host:port, curl style (host:port:addr[,addr...]). Repeatable")
+	runCmd.PersistentFlags().StringArrayVar(&c.ConnectTo, "connect-to", nil, "Connect to HOST2:PORT2 for requests to HOST1:PORT1, curl style (HOST1:PORT1:HOST2:PORT2). Repeatable")
+	runCmd.PersistentFlags().BoolVar(&c.VerifyServer, "verify-server", false, "Cross-check the report against a yahba test server's /_stats")
+	runCmd.PersistentFlags().IntVar(&c.MaxAttempts, "max-attempts", 1, "Maximum attempts per request, including the first (1 disables retries)")
+	runCmd.PersistentFlags().StringVar(&c.RetryStatusCodes, "retry-status-codes", "429,502,503,504", "Status codes that are retried")
+	runCmd.PersistentFlags().StringVar(&c.RetryErrors, "retry-errors", "connection_refused,connection_reset,eof,timeout", "Transport error classes that are retried")
+	runCmd.PersistentFlags().DurationVar(&c.RetryBackoff, "retry-backoff", 100*time.Millisecond, "Initial retry backoff, doubled on each attempt with jitter")
+	runCmd.PersistentFlags().DurationVar(&c.RetryMaxBackoff, "retry-max-backoff", 10*time.Second, "Maximum retry backoff, also caps Retry-After")
+	runCmd.PersistentFlags().StringVar(&c.CertFile, "cert", "", "Client certificate file for mutual TLS (PEM)")
+	runCmd.PersistentFlags().StringVar(&c.KeyFile, "key", "", "Client private key file for mutual TLS (PEM)")
+	runCmd.PersistentFlags().StringVar(&c.CACertFile, "cacert", "", "CA bundle used to verify the server instead of the system roots (PEM)")
+	runCmd.PersistentFlags().StringVar(&c.TLSMinVersion, "tls-min-version", "", "Minimum TLS version (1.0, 1.1, 1.2, 1.3)")
+	runCmd.PersistentFlags().StringVar(&c.TLSMaxVersion, "tls-max-version", "", "Maximum TLS version (1.0, 1.1, 1.2, 1.3)")
+	runCmd.PersistentFlags().StringVar(&c.Ciphers, "ciphers", "", "Comma-separated TLS 1.2 cipher suites (e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256)")
+	runCmd.PersistentFlags().StringVar(&c.SNI, "sni", "", "Server name sent in the TLS handshake and used to verify the certificate")
+	runCmd.PersistentFlags().StringVar(&c.Agents, "agents", "", "Distribute the test across agents (host1:7000,https://host2:7000). Not available with --cert, --key, --cacert, --unix-socket or --sign, which refer to files, variables and sockets on this host")
+	runCmd.PersistentFlags().StringVar(&c.AgentToken, "agent-token", "", "Token the agents expect (default: $YAHBA_AGENT_TOKEN)")
+	runCmd.PersistentFlags().StringVar(&c.AgentCACertFile, "agent-cacert", "", "CA bundle used to verify https agents (PEM)")
 }
 
 func run(ctx context.Context, c config.Config) error {
@@ -107,29 +157,98 @@ func run(ctx context.Context, c config.Config) error {
 		c.ParsedHeaders = parsedHeaders
 	}
 
-	// todo: do we need to create individual jobs if the jobs are all the same?
-	c.Logger.Debug("Creating %d jobs for requests to %s", c.Requests, c.URL)
-	jobs := make([]worker.Job, c.Requests)
-	for i := 0; i < c.Requests; i++ {
-		jobs[i] = worker.Job{ID: i, Host: c.URL, Method: c.Method, Body: c.Body}
+	if c.CookieFile != "" {
+		c.Logger.Debug("Loading cookies from %s", c.CookieFile)
+		cookies, err := client.ParseCookieFile(c.CookieFile)
+		if err != nil {
+			return err
+		}
+		c.ParsedCookies = cookies
+		c.Sessions = true
+	}
+
+	var statsClient *http.Client
+	if c.VerifyServer {
+		var err error
+		if statsClient, err = client.NewClient(c); err != nil {
+			return err
+		}
+
+		c.Logger.Debug("Resetting server stats before the test")
+		if err := server.ResetStats(statsClient, c.URL); err != nil {
+			return fmt.Errorf("error resetting server stats: %w", err)
+		}
 	}
 
-	factory := func(id int, jobChan <-chan worker.Job, resultChan chan<- report.Result, client *http.Client, cfg config.Config) worker.Worker {
-		return *worker.NewWorker(id, jobChan, resultChan, client, cfg)
+	if c.Agents != "" {
+		if c.AgentToken == "" {
+			c.AgentToken = os.Getenv(agent.TokenEnv)
+		}
+		return runDistributed(ctx, c, statsClient)
 	}
 
+	// todo: do we need to create individual jobs if the jobs are all the same?
+	c.Logger.Debug("Creating %d jobs for requests to %s", c.Requests, c.URL)
+	jobs := worker.CreateJobs(c)
+
 	reportChan := make(chan report.Report, c.Requests)
-	go worker.Work(ctx, c, jobs, reportChan, factory)
+	go worker.Work(ctx, c, jobs, reportChan, worker.DefaultFactory)
 
 	select {
 	case <-ctx.Done():
 		c.Logger.Debug("Shutdown signal received. Cleaning up.")
 		return nil
 	case r := <-reportChan:
+		if err := verifyServer(c, statsClient, &r); err != nil {
+			return err
+		}
 		return generateReport(c, r)
 	}
 }
 
+// runDistributed splits the test across the configured agents and reports the merged results
+func runDistributed(ctx context.Context, c config.Config, statsClient *http.Client) error {
+	coordinator, err := agent.NewCoordinator(c)
+	if err != nil {
+		return err
+	}
+
+	c.Logger.Debug("Distributing %d requests across %d agents", c.Requests, len(coordinator.Agents))
+	r, err := coordinator.Run(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			c.Logger.Debug("Shutdown signal received. Cleaning up.")
+			return nil
+		}
+		return fmt.Errorf("distributed test failed: %w", err)
+	}
+
+	if err := verifyServer(c, statsClient, &r); err != nil {
+		return err
+	}
+	return generateReport(c, r)
+}
+
+// verifyServer cross-checks the report against the test server's stats
+// when --verify-server is set
+func verifyServer(c config.Config, statsClient *http.Client, r *report.Report) error {
+	if statsClient == nil {
+		return nil
+	}
+
+	c.Logger.Debug("Fetching server stats to verify the report")
+	stats, err := server.FetchStats(statsClient, c.URL)
+	if err != nil {
+		return fmt.Errorf("error fetching server stats: %w", err)
+	}
+
+	r.VerifyServer(stats)
+	for _, d := range r.ServerVerification.Discrepancies {
+		c.Logger.Warn("Server verification: %s", d)
+	}
+	return nil
+}
+
 func generateReport(c config.Config, r report.Report) error {
 	c.Logger.Debug("Generating report in %s format", c.OutputFormat)
 
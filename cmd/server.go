@@ -22,6 +22,8 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"time"
+
 	"github.com/rnemeth90/yahba/internal/logger"
 	"github.com/rnemeth90/yahba/internal/server"
 	"github.com/spf13/cobra"
@@ -32,13 +34,42 @@ var serverConfig server.Config
 // serverCmd represents the server command
 var serverCmd = &cobra.Command{
 	Use:   "server",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
+	Short: "Start a local test server to run YAHBA against",
+	Long: `Start a local test server to run YAHBA against.
+
+Endpoints:
+  /test                                        200 OK
+  /chunked?size=1024&count=10&interval=100ms   chunked transfer encoding
+  /drip?bytes=100&duration=5s&delay=0s         body drip-fed one byte at a time
+  /bytes/{n}                                   generated payload of n bytes
+  /delay/headers?delay=1s                      delay before the response headers
+  /delay/body?delay=1s                         headers immediately, delayed body
+  /sse?count=10&interval=1s                    Server-Sent Events stream
+  /ws?delay=10ms&close_after=100               WebSocket echo, for yahba ws
+  /_stats                                      request counters as JSON
+  /_stats/reset                                POST to reset the counters
+
+The server can inject faults to rehearse how a load test behaves against a
+misbehaving backend. For example, to add long-tail latency, fail 5% of
+requests with a 503 and reset 1% of connections:
+
+  yahba server --latency 50ms --latency-distribution longtail --error-rates 503:5 --reset-rate 1
+
+It can also stand in for downstream services by serving the routes defined
+in a YAML file, which is reloaded whenever it changes:
+
+  yahba server --routes routes.yaml
+
+Use --tls to serve HTTPS and HTTP/2 with a generated self-signed certificate
+(pair it with yahba run --insecure), or --h2c for HTTP/2 without TLS.
+--http3 adds HTTP/3 over QUIC on the same UDP port, for yahba run --http3.
+
+Run yahba with --verify-server to compare its report with /_stats.
+
+To simulate a service with known limits, give it workers and a queue. This
+one handles at most 4 / 50ms = 80 requests per second and sheds the excess:
 
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
+  yahba server --workers 4 --queue-size 8 --service-time 50ms --shed-status 429`,
 	Run: func(cmd *cobra.Command, args []string) {
 		server := server.Server{
 			Config: &serverConfig,
@@ -53,4 +84,24 @@ to quickly create a Cobra application.`,
 func init() {
 	rootCmd.AddCommand(serverCmd)
 	serverCmd.PersistentFlags().StringVarP(&serverConfig.Port, "port", "p", ":8081", "port to run the server on")
+	serverCmd.PersistentFlags().StringVar(&serverConfig.Faults.LatencyDistribution, "latency-distribution", "fixed", "Latency distribution (fixed, normal, longtail)")
+	serverCmd.PersistentFlags().DurationVar(&serverConfig.Faults.Latency, "latency", 0, "Added latency: fixed delay, normal mean or long-tail median")
+	serverCmd.PersistentFlags().DurationVar(&serverConfig.Faults.LatencyJitter, "latency-jitter", 0, "Standard deviation (normal) or spread (longtail) of the added latency")
+	serverCmd.PersistentFlags().StringVar(&serverConfig.Faults.ErrorRates, "error-rates", "", "Percentage of requests to fail by status code (500:5,503:2.5)")
+	serverCmd.PersistentFlags().Float64Var(&serverConfig.Faults.ResetRate, "reset-rate", 0, "Percentage of requests whose connection is reset")
+	serverCmd.PersistentFlags().Float64Var(&serverConfig.Faults.HangRate, "hang-rate", 0, "Percentage of requests that never receive a response")
+	serverCmd.PersistentFlags().StringVar(&serverConfig.RoutesFile, "routes", "", "YAML file of routes to serve, reloaded when it changes")
+	serverCmd.PersistentFlags().BoolVar(&serverConfig.TLS, "tls", false, "Serve HTTPS and HTTP/2 with a self-signed certificate unless --cert and --key are set")
+	serverCmd.PersistentFlags().StringVar(&serverConfig.CertFile, "cert", "", "TLS certificate file (PEM)")
+	serverCmd.PersistentFlags().StringVar(&serverConfig.KeyFile, "key", "", "TLS private key file (PEM)")
+	serverCmd.PersistentFlags().StringVar(&serverConfig.ClientCAFile, "client-ca", "", "Require client certificates signed by this CA (PEM)")
+	serverCmd.PersistentFlags().BoolVar(&serverConfig.RequireClientCert, "require-client-cert", false, "Require clients to present a certificate")
+	serverCmd.PersistentFlags().BoolVar(&serverConfig.H2C, "h2c", false, "Serve HTTP/2 over cleartext (prior knowledge or upgrade)")
+	serverCmd.PersistentFlags().BoolVar(&serverConfig.HTTP3, "http3", false, "Also serve HTTP/3 over QUIC on the same UDP port (implies --tls)")
+	serverCmd.PersistentFlags().IntVar(&serverConfig.Capacity.Workers, "workers", 0, "Simulate a service that processes this many requests at once (0 disables)")
+	serverCmd.PersistentFlags().IntVar(&serverConfig.Capacity.QueueSize, "queue-size", 0, "Requests queued when every worker is busy before shedding load")
+	serverCmd.PersistentFlags().DurationVar(&serverConfig.Capacity.ServiceTime, "service-time", 10*time.Millisecond, "Time each request holds a worker (the mean when exponential)")
+	serverCmd.PersistentFlags().StringVar(&serverConfig.Capacity.ServiceDistribution, "service-distribution", "fixed", "Service time distribution (fixed, exponential)")
+	serverCmd.PersistentFlags().IntVar(&serverConfig.Capacity.ShedStatus, "shed-status", 503, "Status returned when the queue is full (e.g. 503 or 429)")
+	serverCmd.PersistentFlags().Uint64Var(&serverConfig.Faults.Seed, "seed", 0, "Random seed for reproducible faults (0 picks one at random)")
 }
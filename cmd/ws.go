@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/config"
+	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/rnemeth90/yahba/internal/ws"
+	"github.com/spf13/cobra"
+)
+
+var (
+	wsConfig       ws.Config
+	wsLogLevel     string
+	wsOutputFormat string
+	wsOutputFile   string
+)
+
+// wsCmd represents the ws command
+var wsCmd = &cobra.Command{
+	Use:   "ws",
+	Short: "Run a WebSocket performance test",
+	Long: `Run a WebSocket performance test.
+
+Opens --connections WebSocket connections evenly over --ramp, then each one
+sends --rate messages per second until --duration has passed. Every message
+received is matched to the message on that connection that it echoes, or, if
+it echoes none, to the oldest message still waiting for a reply, so the round
+trip latency suits echo and request/response services. Echoes of messages
+that already timed out are ignored.
+
+Messages are Go templates with {{.Conn}}, {{.Seq}} and {{.Time}}. A message
+file holds one template per line, sent in order and repeated:
+
+  yahba ws --url ws://localhost:8081/ws --connections 100 --ramp 10s --duration 1m --rate 5 \
+    --message '{"type":"ping","conn":{{.Conn}},"seq":{{.Seq}}}'
+
+yahba server serves a WebSocket echo endpoint at /ws to test against.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		wsConfig.Logger = logger.New(wsLogLevel, wsOutputFile, false)
+		if wsOutputFormat == "json" || wsOutputFormat == "yaml" {
+			wsConfig.Logger.Silent = true
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		r, err := ws.Run(ctx, wsConfig)
+		if err != nil {
+			wsConfig.Logger.Error("Application encountered a critical error: %v", err)
+			return
+		}
+
+		out := config.Config{OutputFormat: wsOutputFormat, Logger: wsConfig.Logger}
+		if err := generateReport(out, r); err != nil {
+			wsConfig.Logger.Error("Application encountered a critical error: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(wsCmd)
+	wsCmd.PersistentFlags().StringVarP(&wsConfig.URL, "url", "u", "", "The WebSocket URL to test (ws:// or wss://)")
+	wsCmd.PersistentFlags().IntVarP(&wsConfig.Connections, "connections", "c", 10, "Number of concurrent connections")
+	wsCmd.PersistentFlags().DurationVar(&wsConfig.Ramp, "ramp", 0, "Time over which the connections are opened")
+	wsCmd.PersistentFlags().DurationVarP(&wsConfig.Duration, "duration", "d", 30*time.Second, "How long every connection stays open once the ramp is over")
+	wsCmd.PersistentFlags().Float64Var(&wsConfig.Rate, "rate", 1, "Messages per second per connection (0 only holds the connections open)")
+	wsCmd.PersistentFlags().StringVar(&wsConfig.Message, "message", "", "Message template (default {\"conn\":{{.Conn}},\"seq\":{{.Seq}}})")
+	wsCmd.PersistentFlags().StringVar(&wsConfig.MessageFile, "message-file", "", "File of message templates, one per line, sent in order")
+	wsCmd.PersistentFlags().StringVarP(&wsConfig.Headers, "headers", "H", "", "Headers for the upgrade request (Key1:Value1,Key2:Value2)")
+	wsCmd.PersistentFlags().DurationVarP(&wsConfig.Timeout, "timeout", "t", 10*time.Second, "Handshake timeout, and how long a message waits for its reply")
+	wsCmd.PersistentFlags().BoolVarP(&wsConfig.Insecure, "insecure", "i", false, "Disable SSL/TLS verification")
+	wsCmd.PersistentFlags().StringVarP(&wsLogLevel, "log-level", "l", "error", "Logging level (debug, info, warn, error)")
+	wsCmd.PersistentFlags().StringVarP(&wsOutputFormat, "format", "f", "raw", "Output format (json, yaml, raw)")
+	wsCmd.PersistentFlags().StringVar(&wsOutputFile, "out", "stdout", "Output file (default: stdout)")
+}
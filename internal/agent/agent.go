@@ -0,0 +1,175 @@
+package agent
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/rnemeth90/yahba/internal/report"
+	"github.com/rnemeth90/yahba/internal/worker"
+)
+
+// TokenEnv is the environment variable agents and coordinators read the
+// token from when it is not given as a flag
+const TokenEnv = "YAHBA_AGENT_TOKEN"
+
+// Agent runs load tests on behalf of a coordinator and streams the results
+// back. Only coordinators presenting Token as a bearer token are served.
+type Agent struct {
+	Listen string
+	Token  string
+	// TLSCertFile and TLSKeyFile serve the agent over https, which keeps the
+	// token and any credentials in the test config off the wire
+	TLSCertFile string
+	TLSKeyFile  string
+	Logger      *logger.Logger
+
+	mu      sync.Mutex
+	running bool
+}
+
+// Run starts the agent's HTTP listener
+func (a *Agent) Run() error {
+	if a.Listen == "" {
+		return ErrInvalidListenAddress
+	}
+
+	if a.Token == "" {
+		return ErrMissingToken
+	}
+
+	if (a.TLSCertFile == "") != (a.TLSKeyFile == "") {
+		return ErrInvalidTLSFiles
+	}
+
+	if a.TLSCertFile != "" {
+		a.Logger.Info("Agent listening on %s (https)", a.Listen)
+		return http.ListenAndServeTLS(a.Listen, a.TLSCertFile, a.TLSKeyFile, a.Handler())
+	}
+
+	a.Logger.Info("Agent listening on %s", a.Listen)
+	return http.ListenAndServe(a.Listen, a.Handler())
+}
+
+// Handler returns the agent's HTTP handler
+func (a *Agent) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(RunPath, a.handleRun)
+	return mux
+}
+
+func (a *Agent) handleRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !a.authorized(r) {
+		a.Logger.Warn("Rejected run request from %s: invalid token", r.RemoteAddr)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req RunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid run request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	cfg := req.Config
+	cfg.Logger = a.Logger
+	// never read files, environment variables or sockets on the coordinator's behalf
+	if local := cfg.HostLocal(); len(local) > 0 {
+		http.Error(w, fmt.Sprintf("%v: %s", ErrHostLocalSettings, strings.Join(local, ", ")), http.StatusBadRequest)
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !a.acquire() {
+		http.Error(w, ErrAgentBusy.Error(), http.StatusConflict)
+		return
+	}
+	defer a.release()
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	enc := json.NewEncoder(w)
+	send := func(m Message) error {
+		if err := enc.Encode(m); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	// the coordinator picks a start time in the future so all agents begin together
+	ctx := r.Context()
+	a.Logger.Info("Test of %d requests at %d RPS against %s starts at %s", cfg.Requests, cfg.RPS, cfg.URL, req.StartAt)
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(time.Until(req.StartAt)):
+	}
+
+	results, err := worker.Start(ctx, cfg, worker.CreateJobs(cfg), worker.DefaultFactory)
+	if err != nil {
+		send(Message{Error: err.Error()})
+		return
+	}
+
+	for result := range results {
+		if err := send(Message{Result: newResult(result)}); err != nil {
+			a.Logger.Error("Failed to stream result to coordinator: %v", err)
+			drain(results)
+			return
+		}
+	}
+
+	send(Message{Done: true})
+	a.Logger.Info("Test complete")
+}
+
+// authorized reports whether the request carries the agent's token. An
+// agent without a token accepts nothing.
+func (a *Agent) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && a.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.Token)) == 1
+}
+
+// drain discards remaining results so the worker pool can exit
+func drain(results <-chan report.Result) {
+	for range results {
+	}
+}
+
+func (a *Agent) acquire() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.running {
+		return false
+	}
+	a.running = true
+	return true
+}
+
+func (a *Agent) release() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.running = false
+}
@@ -0,0 +1,245 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/config"
+	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplit(t *testing.T) {
+	assert.Equal(t, []int{4, 3, 3}, split(10, 3))
+	assert.Equal(t, []int{1, 1}, split(2, 2))
+	assert.Equal(t, []int{5}, split(5, 1))
+}
+
+func TestAgentURL(t *testing.T) {
+	assert.Equal(t, "http://host1:7000/run", agentURL("host1:7000"))
+	assert.Equal(t, "https://host1:7000/run", agentURL("https://host1:7000/"))
+}
+
+func TestNewCoordinator(t *testing.T) {
+	cfg := config.Config{Agents: " , ", Requests: 10, RPS: 10, AgentToken: "t"}
+	_, err := NewCoordinator(cfg)
+	assert.ErrorIs(t, err, ErrNoAgents)
+
+	cfg = config.Config{Agents: "a:1,b:2,c:3", Requests: 2, RPS: 10, AgentToken: "t"}
+	_, err = NewCoordinator(cfg)
+	assert.ErrorIs(t, err, ErrTooFewRequests)
+
+	cfg = config.Config{Agents: "a:1, b:2", Requests: 10, RPS: 10}
+	_, err = NewCoordinator(cfg)
+	assert.ErrorIs(t, err, ErrMissingAgentToken)
+
+	cfg = config.Config{Agents: "https://a:1, b:2", Requests: 10, RPS: 10, AgentToken: "t", AuthToken: "secret"}
+	_, err = NewCoordinator(cfg)
+	assert.ErrorIs(t, err, ErrSecretsOverPlaintext)
+
+	cfg = config.Config{Agents: "a:1, b:2", Requests: 10, RPS: 10, AgentToken: "t", Sign: "hmac-sha256", SignSecretEnv: "SECRET"}
+	_, err = NewCoordinator(cfg)
+	if assert.ErrorIs(t, err, ErrHostLocalSettings) {
+		assert.Contains(t, err.Error(), "--sign, --sign-secret-env")
+	}
+
+	cfg = config.Config{Agents: "a:1, b:2", Requests: 10, RPS: 10, AgentToken: "t"}
+	c, err := NewCoordinator(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a:1", "b:2"}, c.Agents)
+}
+
+func TestCoordinatorRun(t *testing.T) {
+	StartDelay = 100 * time.Millisecond
+
+	var hits atomic.Int64
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	var addrs []string
+	for i := 0; i < 3; i++ {
+		a := &Agent{Token: "t0ken", Logger: logger.New("error", "stdout", false)}
+		s := httptest.NewServer(a.Handler())
+		defer s.Close()
+		addrs = append(addrs, strings.TrimPrefix(s.URL, "http://"))
+	}
+
+	cfg := config.Config{
+		URL:        target.URL,
+		Method:     "GET",
+		Requests:   30,
+		RPS:        30,
+		Timeout:    5,
+		Agents:     strings.Join(addrs, ","),
+		AgentToken: "t0ken",
+		Logger:     logger.New("error", "stdout", false),
+	}
+
+	c, err := NewCoordinator(cfg)
+	assert.NoError(t, err)
+
+	r, err := c.Run(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 30, r.TotalRequests)
+	assert.Equal(t, 30, r.Successes)
+	assert.Equal(t, int64(30), hits.Load())
+	assert.Equal(t, 30, r.StatusCodes["200"].Count)
+
+	perAgent := make(map[string]int)
+	for _, result := range r.Results {
+		perAgent[result.Agent]++
+	}
+	for _, addr := range addrs {
+		assert.Equal(t, 10, perAgent[addr])
+	}
+}
+
+func TestCoordinatorRunAgentFailure(t *testing.T) {
+	StartDelay = 100 * time.Millisecond
+
+	a := &Agent{Token: "t0ken", Logger: logger.New("error", "stdout", false)}
+	s := httptest.NewServer(a.Handler())
+	defer s.Close()
+
+	// an invalid config is rejected by the agent before the test starts
+	cfg := config.Config{
+		URL:        "ftp://example.com",
+		Method:     "GET",
+		Requests:   2,
+		RPS:        1,
+		Timeout:    5,
+		Agents:     s.URL,
+		AgentToken: "t0ken",
+		Logger:     logger.New("error", "stdout", false),
+	}
+
+	c, err := NewCoordinator(cfg)
+	assert.NoError(t, err)
+
+	_, err = c.Run(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "400")
+}
+
+func TestAgentRejectsInvalidToken(t *testing.T) {
+	var hits atomic.Int64
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer target.Close()
+
+	for _, token := range []string{"", "t0ken"} {
+		a := &Agent{Token: token, Logger: logger.New("error", "stdout", false)}
+		s := httptest.NewServer(a.Handler())
+		defer s.Close()
+
+		cfg := config.Config{
+			URL:        target.URL,
+			Method:     "GET",
+			Requests:   1,
+			RPS:        1,
+			Timeout:    5,
+			Agents:     s.URL,
+			AgentToken: "wrong",
+			Logger:     logger.New("error", "stdout", false),
+		}
+		c, err := NewCoordinator(cfg)
+		assert.NoError(t, err)
+
+		_, err = c.Run(context.Background())
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "401")
+		}
+	}
+	assert.Equal(t, int64(0), hits.Load())
+}
+
+func TestAgentRejectsHostLocalSettings(t *testing.T) {
+	a := &Agent{Token: "t0ken", Logger: logger.New("error", "stdout", false)}
+	s := httptest.NewServer(a.Handler())
+	defer s.Close()
+
+	// a token holder posting straight to the agent cannot make it read files
+	cfg := config.Config{
+		URL:      "https://localhost/test",
+		Method:   "GET",
+		Requests: 1,
+		RPS:      1,
+		Timeout:  5,
+		CertFile: "/etc/ssl/private/key.pem",
+		KeyFile:  "/etc/ssl/private/key.pem",
+	}
+	body, err := json.Marshal(RunRequest{Config: cfg, StartAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodPost, s.URL+RunPath, bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer t0ken")
+
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Contains(t, string(msg), "--cert, --key")
+}
+
+func TestCoordinatorRunOverTLS(t *testing.T) {
+	StartDelay = 100 * time.Millisecond
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer api-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer target.Close()
+
+	a := &Agent{Token: "t0ken", Logger: logger.New("error", "stdout", false)}
+	s := httptest.NewTLSServer(a.Handler())
+	defer s.Close()
+
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	pemCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw})
+	if err := os.WriteFile(ca, pemCert, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// credentials may be sent to agents served over https
+	cfg := config.Config{
+		URL:             target.URL,
+		Method:          "GET",
+		Requests:        2,
+		RPS:             2,
+		Timeout:         5,
+		Auth:            "bearer",
+		AuthToken:       "api-secret",
+		Agents:          s.URL,
+		AgentToken:      "t0ken",
+		AgentCACertFile: ca,
+		Logger:          logger.New("error", "stdout", false),
+	}
+	c, err := NewCoordinator(cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	r, err := c.Run(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, r.StatusCodes["200"].Count)
+}
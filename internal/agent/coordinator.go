@@ -0,0 +1,198 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/config"
+	"github.com/rnemeth90/yahba/internal/report"
+	"github.com/rnemeth90/yahba/internal/worker"
+)
+
+// StartDelay is how far in the future the coordinator schedules the
+// synchronized start, giving every agent time to receive its share of the test.
+// Agents compare against their own clocks, so hosts should be NTP synced.
+var StartDelay = 2 * time.Second
+
+// Coordinator splits a test across agents and merges their results
+type Coordinator struct {
+	Agents []string
+	Config config.Config
+	Client *http.Client
+}
+
+// NewCoordinator creates a coordinator for the comma-separated agent list in cfg.Agents
+func NewCoordinator(cfg config.Config) (*Coordinator, error) {
+	var agents []string
+	for _, a := range strings.Split(cfg.Agents, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			agents = append(agents, a)
+		}
+	}
+
+	if len(agents) == 0 {
+		return nil, ErrNoAgents
+	}
+
+	if cfg.Requests < len(agents) || cfg.RPS < len(agents) {
+		return nil, ErrTooFewRequests
+	}
+
+	if cfg.AgentToken == "" {
+		return nil, ErrMissingAgentToken
+	}
+
+	if local := cfg.HostLocal(); len(local) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrHostLocalSettings, strings.Join(local, ", "))
+	}
+
+	// the whole config is sent to every agent, so credentials need TLS
+	if cfg.HasSecrets() {
+		for _, a := range agents {
+			if !strings.HasPrefix(agentURL(a), "https://") {
+				return nil, fmt.Errorf("%w: %s", ErrSecretsOverPlaintext, a)
+			}
+		}
+	}
+
+	client := &http.Client{}
+	if cfg.AgentCACertFile != "" {
+		pem, err := os.ReadFile(cfg.AgentCACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading agent CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, config.ErrInvalidCACert
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+		client.Transport = transport
+	}
+
+	return &Coordinator{
+		Agents: agents,
+		Config: cfg,
+		Client: client,
+	}, nil
+}
+
+// Run starts the test on every agent and returns the merged report. If any
+// agent fails, the test is cancelled on all of them.
+func (c *Coordinator) Run(ctx context.Context) (report.Report, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	requests := split(c.Config.Requests, len(c.Agents))
+	rps := split(c.Config.RPS, len(c.Agents))
+	startAt := time.Now().Add(StartDelay)
+
+	results := make(chan report.Result, c.Config.Requests)
+	errs := make(chan error, len(c.Agents))
+	wg := &sync.WaitGroup{}
+
+	for i, addr := range c.Agents {
+		cfg := c.Config
+		cfg.Agents = ""
+		// the cookies were read here and travel in ParsedCookies
+		cfg.CookieFile = ""
+		cfg.Requests = requests[i]
+		cfg.RPS = rps[i]
+
+		wg.Add(1)
+		go func(addr string, cfg config.Config) {
+			defer wg.Done()
+			if err := c.runAgent(ctx, addr, RunRequest{Config: cfg, StartAt: startAt}, results); err != nil {
+				errs <- fmt.Errorf("agent %s: %w", addr, err)
+				cancel()
+			}
+		}(addr, cfg)
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+		close(errs)
+	}()
+
+	r := worker.ProcessResults(c.Config, results)
+	if err := <-errs; err != nil {
+		return report.Report{}, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return report.Report{}, err
+	}
+
+	return r, nil
+}
+
+// runAgent posts the run request to a single agent and forwards its results
+func (c *Coordinator) runAgent(ctx context.Context, addr string, runReq RunRequest, results chan<- report.Result) error {
+	body, err := json.Marshal(runReq)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, agentURL(addr), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.Config.AgentToken)
+
+	c.Config.Logger.Debug("Starting %d requests at %d RPS on agent %s", runReq.Config.Requests, runReq.Config.RPS, addr)
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var m Message
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			return fmt.Errorf("invalid message: %w", err)
+		}
+
+		switch {
+		case m.Error != "":
+			return fmt.Errorf("%s", m.Error)
+		case m.Done:
+			c.Config.Logger.Debug("Agent %s finished", addr)
+			return nil
+		case m.Result != nil:
+			results <- m.Result.toReport(addr)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return ErrIncompleteStream
+}
+
+// agentURL turns an agent address (host:port or a full URL) into its run endpoint
+func agentURL(addr string) string {
+	if !strings.HasPrefix(addr, "http://") && !strings.HasPrefix(addr, "https://") {
+		addr = "http://" + addr
+	}
+	return strings.TrimSuffix(addr, "/") + RunPath
+}
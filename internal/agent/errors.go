@@ -0,0 +1,16 @@
+package agent
+
+import "errors"
+
+var (
+	ErrInvalidListenAddress = errors.New("invalid listen address")
+	ErrNoAgents             = errors.New("at least one agent address is required")
+	ErrTooFewRequests       = errors.New("requests and RPS must be at least the number of agents")
+	ErrAgentBusy            = errors.New("agent is already running a test")
+	ErrIncompleteStream     = errors.New("agent closed the result stream before the test finished")
+	ErrMissingToken         = errors.New("an agent token is required, set --token or YAHBA_AGENT_TOKEN")
+	ErrMissingAgentToken    = errors.New("an agent token is required, set --agent-token or YAHBA_AGENT_TOKEN")
+	ErrInvalidTLSFiles      = errors.New("a TLS certificate and key must both be provided")
+	ErrSecretsOverPlaintext = errors.New("the test config contains credentials, which are only sent to https:// agents")
+	ErrHostLocalSettings    = errors.New("files, environment variables, sockets and request signing refer to the host they are set on and cannot be used with agents")
+)
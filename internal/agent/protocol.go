@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"errors"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/config"
+	"github.com/rnemeth90/yahba/internal/report"
+)
+
+// RunPath is the agent endpoint the coordinator posts a RunRequest to
+const RunPath = "/run"
+
+// RunRequest is sent by the coordinator to start a test on an agent
+type RunRequest struct {
+	Config  config.Config `json:"config"`
+	StartAt time.Time     `json:"start_at"`
+}
+
+// Message is a single line of the newline-delimited JSON stream an agent
+// sends back while a test is running. Exactly one field is set.
+type Message struct {
+	Result *Result `json:"result,omitempty"`
+	Error  string  `json:"error,omitempty"`
+	Done   bool    `json:"done,omitempty"`
+}
+
+// Result wraps report.Result so the error survives the round trip as a string
+type Result struct {
+	report.Result
+	Error string `json:"error"`
+}
+
+func newResult(r report.Result) *Result {
+	res := &Result{Result: r}
+	if r.Error != nil {
+		res.Error = r.Error.Error()
+	}
+	return res
+}
+
+func (r *Result) toReport(agent string) report.Result {
+	res := r.Result
+	if r.Error != "" {
+		res.Error = errors.New(r.Error)
+	}
+	res.Agent = agent
+	return res
+}
+
+// split divides total as evenly as possible into n parts, giving any
+// remainder to the first parts
+func split(total, n int) []int {
+	parts := make([]int, n)
+	for i := range parts {
+		parts[i] = total / n
+		if i < total%n {
+			parts[i]++
+		}
+	}
+	return parts
+}
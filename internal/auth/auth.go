@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/config"
+)
+
+// Supported authentication schemes
+const (
+	Basic  = "basic"
+	Digest = "digest"
+	Bearer = "bearer"
+	OAuth2 = "oauth2"
+)
+
+// Provider adds credentials to requests
+type Provider interface {
+	// Apply adds credentials to req, fetching them first if needed
+	Apply(req *http.Request) error
+	// Challenge is called with every 401 response and reports whether the
+	// request should be sent again with fresh credentials
+	Challenge(resp *http.Response) bool
+}
+
+// New returns the provider for the configured scheme, or nil when
+// authentication is disabled. tokens is used for token requests, which go to
+// the token endpoint rather than the target.
+func New(cfg config.Config, tokens http.RoundTripper) Provider {
+	switch cfg.Auth {
+	case Basic:
+		return &basicProvider{user: cfg.AuthUser, password: cfg.AuthPassword}
+	case Digest:
+		return &digestProvider{user: cfg.AuthUser, password: cfg.AuthPassword}
+	case Bearer:
+		return &bearerProvider{token: cfg.AuthToken}
+	case OAuth2:
+		return newOAuth2Provider(cfg, tokens)
+	default:
+		return nil
+	}
+}
+
+// Timing records the time a request spent on authentication, waiting for
+// tokens and answering challenges, and how many times it was sent again
+// after a challenge. The time is excluded from request latency.
+type Timing struct {
+	mu       sync.Mutex
+	duration time.Duration
+	resent   int
+}
+
+// Duration returns the time spent on authentication
+func (t *Timing) Duration() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.duration
+}
+
+// Resent returns how many times the request was sent again after a
+// challenge
+func (t *Timing) Resent() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.resent
+}
+
+func (t *Timing) add(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.duration += d
+}
+
+type timingKey struct{}
+
+// WithTiming returns a context whose authentication time is recorded in t
+func WithTiming(ctx context.Context, t *Timing) context.Context {
+	return context.WithValue(ctx, timingKey{}, t)
+}
+
+// record adds the time since start to the timing attached to ctx, if any
+func record(ctx context.Context, start time.Time) {
+	if t, ok := ctx.Value(timingKey{}).(*Timing); ok {
+		t.add(time.Since(start))
+	}
+}
+
+// recordResend counts a challenged request being sent again
+func recordResend(ctx context.Context) {
+	if t, ok := ctx.Value(timingKey{}).(*Timing); ok {
+		t.mu.Lock()
+		t.resent++
+		t.mu.Unlock()
+	}
+}
+
+// Transport authenticates requests with a provider before passing them to
+// the underlying round tripper. Challenged requests are sent once more.
+type Transport struct {
+	Base     http.RoundTripper
+	Provider Provider
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	// providers record the time they spend fetching credentials themselves
+	authenticated := req.Clone(ctx)
+	if err := t.Provider.Apply(authenticated); err != nil {
+		return nil, err
+	}
+
+	// the rejected round trip is a request like any other; only answering
+	// the challenge is authentication time
+	resp, err := t.Base.RoundTrip(authenticated)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !rewindable(req) {
+		return resp, err
+	}
+	challengeStart := time.Now()
+	if !t.Provider.Challenge(resp) {
+		return resp, nil
+	}
+
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	retry := req.Clone(ctx)
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	record(ctx, challengeStart)
+	if err := t.Provider.Apply(retry); err != nil {
+		return nil, err
+	}
+
+	recordResend(ctx)
+	return t.Base.RoundTrip(retry)
+}
+
+// rewindable reports whether req's body can be sent again
+func rewindable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
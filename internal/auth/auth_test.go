@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// send makes a request through an authenticating transport and returns the
+// response along with the time spent on authentication
+func send(t *testing.T, tr *Transport, method, url, body string) (*http.Response, time.Duration) {
+	t.Helper()
+	resp, timing := sendTimed(t, tr, method, url, body)
+	return resp, timing.Duration()
+}
+
+// sendTimed is send returning the whole authentication timing
+func sendTimed(t *testing.T, tr *Transport, method, url, body string) (*http.Response, *Timing) {
+	t.Helper()
+	timing := &Timing{}
+	req, err := http.NewRequestWithContext(WithTiming(context.Background(), timing), method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := (&http.Client{Transport: tr}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp, timing
+}
+
+func TestNew(t *testing.T) {
+	assert.Nil(t, New(config.Config{}, http.DefaultTransport))
+	assert.IsType(t, &basicProvider{}, New(config.Config{Auth: Basic}, http.DefaultTransport))
+	assert.IsType(t, &digestProvider{}, New(config.Config{Auth: Digest}, http.DefaultTransport))
+	assert.IsType(t, &bearerProvider{}, New(config.Config{Auth: Bearer}, http.DefaultTransport))
+	assert.IsType(t, &oauth2Provider{}, New(config.Config{Auth: OAuth2}, http.DefaultTransport))
+}
+
+func TestStaticCredentials(t *testing.T) {
+	var got string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Authorization")
+	}))
+	defer ts.Close()
+
+	// static credentials never delay a request
+	_, timing := sendTimed(t, &Transport{Base: http.DefaultTransport, Provider: &basicProvider{user: "user", password: "secret"}}, "GET", ts.URL, "")
+	assert.Equal(t, "Basic dXNlcjpzZWNyZXQ=", got)
+	assert.Zero(t, timing.Duration())
+
+	_, timing = sendTimed(t, &Transport{Base: http.DefaultTransport, Provider: &bearerProvider{token: "abc"}}, "GET", ts.URL, "")
+	assert.Equal(t, "Bearer abc", got)
+	assert.Zero(t, timing.Duration())
+}
+
+// challengeOnce rejects the first request without a token
+type challengeOnce struct {
+	challenged bool
+}
+
+func (p *challengeOnce) Apply(req *http.Request) error {
+	if p.challenged {
+		req.Header.Set("Authorization", "Bearer fresh")
+	}
+	return nil
+}
+
+func (p *challengeOnce) Challenge(*http.Response) bool {
+	p.challenged = true
+	return true
+}
+
+func TestChallengeResendsBody(t *testing.T) {
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if r.Header.Get("Authorization") == "" {
+			time.Sleep(20 * time.Millisecond)
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer ts.Close()
+
+	resp, timing := sendTimed(t, &Transport{Base: http.DefaultTransport, Provider: &challengeOnce{}}, "POST", ts.URL, `{"a":1}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{`{"a":1}`, `{"a":1}`}, bodies)
+	assert.Equal(t, 1, timing.Resent())
+	// the rejected round trip is part of the request's latency
+	assert.Less(t, timing.Duration(), 20*time.Millisecond)
+}
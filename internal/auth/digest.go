@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// digestProvider answers HTTP digest challenges (RFC 7616). The first
+// request is sent without credentials to obtain a challenge, which is then
+// reused with an increasing nonce count until the server issues a new one.
+type digestProvider struct {
+	user     string
+	password string
+
+	mu        sync.Mutex
+	challenge *digestChallenge
+	count     int
+}
+
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+}
+
+func (p *digestProvider) Apply(req *http.Request) error {
+	p.mu.Lock()
+	c := p.challenge
+	if c == nil {
+		p.mu.Unlock()
+		return nil
+	}
+	p.count++
+	nc := fmt.Sprintf("%08x", p.count)
+	p.mu.Unlock()
+
+	cnonce, err := newCnonce()
+	if err != nil {
+		return err
+	}
+
+	h := c.hash()
+	uri := req.URL.RequestURI()
+	ha1 := h(p.user + ":" + c.realm + ":" + p.password)
+	ha2 := h(req.Method + ":" + uri)
+
+	var response string
+	if c.qop == "" {
+		response = h(ha1 + ":" + c.nonce + ":" + ha2)
+	} else {
+		response = h(ha1 + ":" + c.nonce + ":" + nc + ":" + cnonce + ":" + c.qop + ":" + ha2)
+	}
+
+	fields := []string{
+		fmt.Sprintf("username=%q", p.user),
+		fmt.Sprintf("realm=%q", c.realm),
+		fmt.Sprintf("nonce=%q", c.nonce),
+		fmt.Sprintf("uri=%q", uri),
+		fmt.Sprintf("response=%q", response),
+	}
+	if c.algorithm != "" {
+		fields = append(fields, "algorithm="+c.algorithm)
+	}
+	if c.qop != "" {
+		fields = append(fields, "qop="+c.qop, "nc="+nc, fmt.Sprintf("cnonce=%q", cnonce))
+	}
+	if c.opaque != "" {
+		fields = append(fields, fmt.Sprintf("opaque=%q", c.opaque))
+	}
+
+	req.Header.Set("Authorization", "Digest "+strings.Join(fields, ", "))
+	return nil
+}
+
+func (p *digestProvider) Challenge(resp *http.Response) bool {
+	c, err := parseDigestChallenge(resp.Header.Get("WWW-Authenticate"))
+	if err != nil {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.challenge = c
+	p.count = 0
+	return true
+}
+
+// parseDigestChallenge parses a WWW-Authenticate: Digest header
+func parseDigestChallenge(header string) (*digestChallenge, error) {
+	scheme, params, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Digest") {
+		return nil, ErrInvalidChallenge
+	}
+
+	values := make(map[string]string)
+	for _, param := range splitParams(params) {
+		k, v, ok := strings.Cut(param, "=")
+		if !ok {
+			continue
+		}
+		values[strings.ToLower(strings.TrimSpace(k))] = strings.Trim(strings.TrimSpace(v), `"`)
+	}
+
+	c := &digestChallenge{
+		realm:     values["realm"],
+		nonce:     values["nonce"],
+		opaque:    values["opaque"],
+		algorithm: values["algorithm"],
+	}
+	if c.nonce == "" {
+		return nil, ErrInvalidChallenge
+	}
+
+	switch strings.ToUpper(c.algorithm) {
+	case "", "MD5", "SHA-256":
+	default:
+		return nil, ErrInvalidChallenge
+	}
+
+	for _, qop := range strings.Split(values["qop"], ",") {
+		if strings.TrimSpace(qop) == "auth" {
+			c.qop = "auth"
+		}
+	}
+	return c, nil
+}
+
+// splitParams splits comma-separated auth parameters, ignoring commas
+// inside quoted values
+func splitParams(s string) []string {
+	var params []string
+	var quoted bool
+	start := 0
+	for i, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			params = append(params, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(params, s[start:])
+}
+
+// hash returns the hex digest function for the challenge's algorithm
+func (c *digestChallenge) hash() func(string) string {
+	newHash := md5.New
+	if strings.EqualFold(c.algorithm, "SHA-256") {
+		newHash = sha256.New
+	}
+	return func(s string) string {
+		h := newHash()
+		h.Write([]byte(s))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+}
+
+func newCnonce() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
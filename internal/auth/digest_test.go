@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// digestServer verifies digest credentials for user:secret and counts the
+// challenges it issues
+func digestServer(t *testing.T, algorithm string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var challenges atomic.Int32
+	newHash := md5.New
+	if algorithm == "SHA-256" {
+		newHash = sha256.New
+	}
+	h := func(s string) string {
+		d := newHash()
+		d.Write([]byte(s))
+		return hex.EncodeToString(d.Sum(nil))
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Digest ")
+		params := map[string]string{}
+		for _, p := range splitParams(header) {
+			k, v, _ := strings.Cut(p, "=")
+			params[strings.TrimSpace(k)] = strings.Trim(v, `"`)
+		}
+
+		ha1 := h("user:test:secret")
+		ha2 := h(r.Method + ":" + params["uri"])
+		expected := h(ha1 + ":n0nce:" + params["nc"] + ":" + params["cnonce"] + ":auth:" + ha2)
+		if !ok || params["response"] != expected || params["opaque"] != "op" {
+			challenges.Add(1)
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Digest realm="test", qop="auth,auth-int", nonce="n0nce", opaque="op", algorithm=%s`, algorithm))
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &challenges
+}
+
+func TestDigest(t *testing.T) {
+	for _, algorithm := range []string{"MD5", "SHA-256"} {
+		t.Run(algorithm, func(t *testing.T) {
+			ts, challenges := digestServer(t, algorithm)
+			tr := &Transport{Base: http.DefaultTransport, Provider: &digestProvider{user: "user", password: "secret"}}
+
+			for i := 0; i < 3; i++ {
+				resp, _ := send(t, tr, "GET", ts.URL+"/items?page=1", "")
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+			}
+			// later requests reuse the challenge
+			assert.Equal(t, int32(1), challenges.Load())
+		})
+	}
+}
+
+func TestDigestWrongPassword(t *testing.T) {
+	ts, _ := digestServer(t, "MD5")
+	tr := &Transport{Base: http.DefaultTransport, Provider: &digestProvider{user: "user", password: "wrong"}}
+
+	resp, _ := send(t, tr, "GET", ts.URL, "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestParseDigestChallenge(t *testing.T) {
+	c, err := parseDigestChallenge(`Digest realm="a, b", nonce="abc", qop="auth-int,auth", algorithm=SHA-256`)
+	assert.NoError(t, err)
+	assert.Equal(t, "a, b", c.realm)
+	assert.Equal(t, "abc", c.nonce)
+	assert.Equal(t, "auth", c.qop)
+	assert.Equal(t, "SHA-256", c.algorithm)
+
+	for _, header := range []string{`Basic realm="x"`, `Digest realm="x"`, `Digest nonce="x", algorithm=SHA-512`} {
+		_, err := parseDigestChallenge(header)
+		assert.ErrorIs(t, err, ErrInvalidChallenge, header)
+	}
+}
@@ -0,0 +1,9 @@
+package auth
+
+import "errors"
+
+var (
+	ErrTokenRequest     = errors.New("token request failed")
+	ErrInvalidToken     = errors.New("token response did not contain an access token")
+	ErrInvalidChallenge = errors.New("invalid digest challenge")
+)
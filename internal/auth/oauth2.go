@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/config"
+)
+
+// maxRefreshMargin caps how long before expiry a token is refreshed
+const maxRefreshMargin = 30 * time.Second
+
+// oauth2Provider fetches tokens with the OAuth2 client credentials grant and
+// shares them between all workers, refreshing shortly before they expire
+type oauth2Provider struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scopes       string
+	client       *http.Client
+
+	mu      sync.RWMutex
+	token   string
+	refresh time.Time
+	// fetching is held while a token is fetched, so workers that need a new
+	// token wait for a single fetch
+	fetching sync.Mutex
+}
+
+func newOAuth2Provider(cfg config.Config, tokens http.RoundTripper) *oauth2Provider {
+	return &oauth2Provider{
+		tokenURL:     cfg.OAuth2TokenURL,
+		clientID:     cfg.OAuth2ClientID,
+		clientSecret: cfg.OAuth2ClientSecret,
+		scopes:       cfg.OAuth2Scopes,
+		client:       &http.Client{Transport: tokens, Timeout: time.Duration(cfg.Timeout) * time.Second},
+	}
+}
+
+// Apply adds the current token, fetching a new one first if it is missing or
+// about to expire. Workers wait for a single fetch instead of each fetching.
+func (p *oauth2Provider) Apply(req *http.Request) error {
+	token, ok := p.current()
+	if !ok {
+		start := time.Now()
+		var err error
+		token, err = p.renew()
+		// a failed fetch is auth time too, it is not the target's latency
+		record(req.Context(), start)
+		if err != nil {
+			return err
+		}
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// current returns the token and whether it can still be used
+func (p *oauth2Provider) current() (string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.token, p.token != "" && time.Now().Before(p.refresh)
+}
+
+// renew fetches a new token, unless another worker did while this one
+// waited for its turn
+func (p *oauth2Provider) renew() (string, error) {
+	p.fetching.Lock()
+	defer p.fetching.Unlock()
+
+	if token, ok := p.current(); ok {
+		return token, nil
+	}
+
+	token, refresh, err := p.fetch()
+	if err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.token = token
+	p.refresh = refresh
+	return token, nil
+}
+
+// Challenge drops the current token so the request is retried with a new one
+func (p *oauth2Provider) Challenge(resp *http.Response) bool {
+	sent := strings.TrimPrefix(resp.Request.Header.Get("Authorization"), "Bearer ")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// another worker may already have replaced the rejected token
+	if p.token == sent {
+		p.token = ""
+	}
+	return true
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// fetch requests a new token and returns it along with when to refresh it
+func (p *oauth2Provider) fetch() (string, time.Time, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if p.scopes != "" {
+		form.Set("scope", strings.Join(strings.Split(p.scopes, ","), " "))
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.clientID), url.QueryEscape(p.clientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%w: %w", ErrTokenRequest, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("%w: %s", ErrTokenRequest, resp.Status)
+	}
+
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", time.Time{}, fmt.Errorf("%w: %w", ErrTokenRequest, err)
+	}
+	if token.AccessToken == "" {
+		return "", time.Time{}, ErrInvalidToken
+	}
+
+	return token.AccessToken, time.Now().Add(refreshAfter(time.Duration(token.ExpiresIn) * time.Second)), nil
+}
+
+// refreshAfter returns how long a token with the given lifetime is used
+// before it is refreshed. Tokens without a lifetime are used until rejected.
+func refreshAfter(lifetime time.Duration) time.Duration {
+	if lifetime <= 0 {
+		return 100 * 365 * 24 * time.Hour
+	}
+	return lifetime - min(lifetime/10, maxRefreshMargin)
+}
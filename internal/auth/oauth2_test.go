@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// tokenServer issues numbered tokens that expire after lifetime and an API
+// that only accepts the newest token
+func tokenServer(t *testing.T, lifetime int, delay time.Duration) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var issued atomic.Int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "yahba" || secret != "s3cret" || r.FormValue("grant_type") != "client_credentials" || r.FormValue("scope") != "read write" {
+			http.Error(w, "invalid_client", http.StatusUnauthorized)
+			return
+		}
+		time.Sleep(delay)
+		n := issued.Add(1)
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"token_type":   "Bearer",
+			"expires_in":   lifetime,
+		})
+	})
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != fmt.Sprintf("Bearer token-%d", issued.Load()) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts, &issued
+}
+
+func oauth2Transport(ts *httptest.Server) *Transport {
+	cfg := config.Config{
+		Timeout:            5,
+		OAuth2TokenURL:     ts.URL + "/token",
+		OAuth2ClientID:     "yahba",
+		OAuth2ClientSecret: "s3cret",
+		OAuth2Scopes:       "read,write",
+	}
+	return &Transport{Base: http.DefaultTransport, Provider: newOAuth2Provider(cfg, http.DefaultTransport)}
+}
+
+func TestOAuth2SharesTokens(t *testing.T) {
+	ts, issued := tokenServer(t, 300, 50*time.Millisecond)
+	tr := oauth2Transport(ts)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, authTime := send(t, tr, "GET", ts.URL+"/api", "")
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.GreaterOrEqual(t, authTime, 50*time.Millisecond)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), issued.Load())
+
+	// the cached token costs nothing
+	_, authTime := send(t, tr, "GET", ts.URL+"/api", "")
+	assert.Zero(t, authTime)
+}
+
+func TestOAuth2Refresh(t *testing.T) {
+	ts, issued := tokenServer(t, 1, 0)
+	tr := oauth2Transport(ts)
+
+	resp, _ := send(t, tr, "GET", ts.URL+"/api", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// refreshed 10% before the one second lifetime ends
+	time.Sleep(950 * time.Millisecond)
+	resp, _ = send(t, tr, "GET", ts.URL+"/api", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), issued.Load())
+}
+
+func TestOAuth2RevokedToken(t *testing.T) {
+	ts, issued := tokenServer(t, 300, 0)
+	tr := oauth2Transport(ts)
+	send(t, tr, "GET", ts.URL+"/api", "")
+
+	// the server rotates its token; the rejected request fetches a new one
+	issued.Add(1)
+	resp, _ := send(t, tr, "GET", ts.URL+"/api", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), issued.Load())
+}
+
+func TestOAuth2InvalidClient(t *testing.T) {
+	ts, _ := tokenServer(t, 300, 0)
+	tr := oauth2Transport(ts)
+	tr.Provider.(*oauth2Provider).clientSecret = "wrong"
+
+	_, err := (&http.Client{Transport: tr}).Get(ts.URL + "/api")
+	assert.ErrorIs(t, err, ErrTokenRequest)
+}
+
+func TestRefreshAfter(t *testing.T) {
+	assert.Equal(t, 270*time.Second, refreshAfter(5*time.Minute))
+	assert.Equal(t, 30*time.Minute, refreshAfter(30*time.Minute+30*time.Second))
+	assert.Equal(t, 900*time.Millisecond, refreshAfter(time.Second))
+}
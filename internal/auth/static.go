@@ -0,0 +1,32 @@
+package auth
+
+import "net/http"
+
+// basicProvider sends HTTP basic credentials with every request
+type basicProvider struct {
+	user     string
+	password string
+}
+
+func (p *basicProvider) Apply(req *http.Request) error {
+	req.SetBasicAuth(p.user, p.password)
+	return nil
+}
+
+func (p *basicProvider) Challenge(*http.Response) bool {
+	return false
+}
+
+// bearerProvider sends a static bearer token with every request
+type bearerProvider struct {
+	token string
+}
+
+func (p *bearerProvider) Apply(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+p.token)
+	return nil
+}
+
+func (p *bearerProvider) Challenge(*http.Response) bool {
+	return false
+}
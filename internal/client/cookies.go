@@ -0,0 +1,105 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// httpOnlyPrefix marks HttpOnly cookies in Netscape cookie files
+const httpOnlyPrefix = "#HttpOnly_"
+
+// ParseCookieFile reads cookies from a Netscape format cookie file, as
+// written by curl -c or browser extensions
+func ParseCookieFile(path string) ([]*http.Cookie, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening cookie file: %w", err)
+	}
+	defer f.Close()
+
+	var cookies []*http.Cookie
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+
+		httpOnly := strings.HasPrefix(text, httpOnlyPrefix)
+		text = strings.TrimPrefix(text, httpOnlyPrefix)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		// domain, include subdomains, path, secure, expiry, name, value
+		fields := strings.Split(text, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("%w: line %d", ErrInvalidCookieFile, line)
+		}
+		expiry, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d", ErrInvalidCookieFile, line)
+		}
+
+		cookie := &http.Cookie{
+			Domain:   fields[0],
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Name:     fields[5],
+			Value:    fields[6],
+			HttpOnly: httpOnly,
+		}
+		if expiry > 0 {
+			cookie.Expires = time.Unix(expiry, 0)
+		}
+		// a leading dot marks cookies that include subdomains, the rest are
+		// host-only
+		cookie.Domain = strings.TrimPrefix(cookie.Domain, ".")
+		if strings.EqualFold(fields[1], "TRUE") {
+			cookie.Domain = "." + cookie.Domain
+		}
+		cookies = append(cookies, cookie)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading cookie file: %w", err)
+	}
+	return cookies, nil
+}
+
+// NewSessionClient returns a client sharing base's transport with its own
+// cookie jar, seeded with cookies, so every virtual user keeps its own
+// session. Cookie domains follow ParseCookieFile: a leading dot includes
+// subdomains.
+func NewSessionClient(base *http.Client, cookies []*http.Cookie) (*http.Client, error) {
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range cookies {
+		host := strings.TrimPrefix(c.Domain, ".")
+		scheme := "http"
+		if c.Secure {
+			scheme = "https"
+		}
+
+		seed := *c
+		if !strings.HasPrefix(c.Domain, ".") {
+			seed.Domain = ""
+		}
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: host, Path: c.Path}, []*http.Cookie{&seed})
+	}
+
+	return &http.Client{
+		Transport: base.Transport,
+		Timeout:   base.Timeout,
+		Jar:       jar,
+	}, nil
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const cookieFile = `# Netscape HTTP Cookie File
+example.test	FALSE	/	FALSE	0	session	abc123
+.example.test	TRUE	/	TRUE	4102444800	tracking	xyz
+#HttpOnly_example.test	FALSE	/api	FALSE	0	token	t0k3n
+`
+
+func TestParseCookieFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	assert.NoError(t, os.WriteFile(path, []byte(cookieFile), 0600))
+
+	cookies, err := ParseCookieFile(path)
+	assert.NoError(t, err)
+	if !assert.Len(t, cookies, 3) {
+		return
+	}
+
+	assert.Equal(t, "example.test", cookies[0].Domain)
+	assert.Equal(t, "abc123", cookies[0].Value)
+	assert.True(t, cookies[0].Expires.IsZero())
+	assert.Equal(t, ".example.test", cookies[1].Domain)
+	assert.True(t, cookies[1].Secure)
+	assert.Equal(t, int64(4102444800), cookies[1].Expires.Unix())
+	assert.True(t, cookies[2].HttpOnly)
+	assert.Equal(t, "/api", cookies[2].Path)
+
+	assert.NoError(t, os.WriteFile(path, []byte("example.test\tFALSE\t/\n"), 0600))
+	_, err = ParseCookieFile(path)
+	assert.ErrorIs(t, err, ErrInvalidCookieFile)
+}
+
+func TestNewSessionClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	assert.NoError(t, os.WriteFile(path, []byte(cookieFile), 0600))
+	cookies, err := ParseCookieFile(path)
+	assert.NoError(t, err)
+
+	base := &http.Client{}
+	session, err := NewSessionClient(base, cookies)
+	assert.NoError(t, err)
+	assert.NotSame(t, base, session)
+
+	names := func(raw string) []string {
+		u, _ := url.Parse(raw)
+		var n []string
+		for _, c := range session.Jar.Cookies(u) {
+			n = append(n, c.Name)
+		}
+		return n
+	}
+
+	assert.ElementsMatch(t, []string{"session"}, names("http://example.test/"))
+	assert.ElementsMatch(t, []string{"session", "tracking", "token"}, names("https://example.test/api/items"))
+	// only the domain cookie reaches subdomains
+	assert.ElementsMatch(t, []string{"tracking"}, names("https://www.example.test/"))
+
+	other, err := NewSessionClient(base, nil)
+	assert.NoError(t, err)
+	u, _ := url.Parse("http://example.test/")
+	assert.Empty(t, other.Jar.Cookies(u))
+}
@@ -0,0 +1,254 @@
+package client
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// ConnStats records the connections dialed on behalf of a request and the
+// time spent establishing proxy tunnels. Attach one to the request context
+// with WithConnStats.
+type ConnStats struct {
+	mu        sync.Mutex
+	opened    int
+	dialStart time.Time
+	dialed    string
+	proxyTime time.Duration
+}
+
+// Opened returns the number of connections dialed
+func (s *ConnStats) Opened() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.opened
+}
+
+// DialedIP returns the IP address of the last connection dialed, whether or
+// not the dial succeeded. It is empty when the request reused a connection
+// or dialed a host name.
+func (s *ConnStats) DialedIP() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dialed
+}
+
+// ProxyTime returns how long it took to establish the proxy tunnel, or zero
+// when the request did not open one
+func (s *ConnStats) ProxyTime() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.proxyTime
+}
+
+type connStatsKey struct{}
+
+// WithConnStats returns a context whose dials are recorded in s
+func WithConnStats(ctx context.Context, s *ConnStats) context.Context {
+	return context.WithValue(ctx, connStatsKey{}, s)
+}
+
+// connStats returns the stats attached to ctx, if any
+func connStats(ctx context.Context) *ConnStats {
+	s, _ := ctx.Value(connStatsKey{}).(*ConnStats)
+	return s
+}
+
+// markDial records the start of a dial
+func markDial(ctx context.Context) {
+	if s := connStats(ctx); s != nil {
+		s.mu.Lock()
+		s.dialStart = time.Now()
+		s.mu.Unlock()
+	}
+}
+
+// markDialedIP records the IP address in addr as the dial target
+func markDialedIP(ctx context.Context, addr string) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) == nil {
+		return
+	}
+	if s := connStats(ctx); s != nil {
+		s.mu.Lock()
+		s.dialed = host
+		s.mu.Unlock()
+	}
+}
+
+// countConn records a successful dial
+func countConn(ctx context.Context) {
+	if s := connStats(ctx); s != nil {
+		s.mu.Lock()
+		s.opened++
+		s.mu.Unlock()
+	}
+}
+
+// tunnelEstablished records the time since the last dial started as the
+// proxy tunnel time
+func tunnelEstablished(ctx context.Context) {
+	if s := connStats(ctx); s != nil {
+		s.mu.Lock()
+		s.proxyTime = time.Since(s.dialStart)
+		s.mu.Unlock()
+	}
+}
+
+// onProxyConnectResponse times CONNECT tunnels through HTTP proxies
+func onProxyConnectResponse(ctx context.Context, _ *url.URL, _ *http.Request, resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		tunnelEstablished(ctx)
+	}
+	return nil
+}
+
+// countingDialer dials through the shared dialer and counts every connection
+// it opens. Transports dial with the context of the request that needed the
+// connection, so the count lands on that request. With source addresses
+// configured, each connection is bound to the next one. With a Unix socket,
+// every connection goes to the socket whatever the address. With a SOCKS5
+// proxy, connections are tunneled through it. Host overrides and address
+// spreading change which address of the target a connection goes to.
+type countingDialer struct {
+	*net.Dialer
+	sources    *sourceRotator
+	overrides  *hostOverrides
+	spread     *addrSpreader
+	unixSocket string
+
+	socks proxy.ContextDialer
+	// socksResolve resolves host names locally before asking the SOCKS5
+	// proxy to connect (socks5://), instead of leaving it to the proxy
+	// (socks5h://)
+	socksResolve bool
+}
+
+func (d countingDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	addr, err := d.target(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.socks == nil {
+		return d.dial(ctx, network, addr)
+	}
+
+	if d.socksResolve {
+		if addr, err = resolveAddr(ctx, d.Dialer, addr); err != nil {
+			return nil, err
+		}
+	}
+
+	markDial(ctx)
+	conn, err := d.socks.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	tunnelEstablished(ctx)
+
+	// report the end of the tunnel rather than the proxy as the peer, so
+	// reused connections are attributed to the backend they reach
+	if remote, err := netip.ParseAddrPort(addr); err == nil {
+		return tunnelConn{Conn: conn, remote: net.TCPAddrFromAddrPort(remote)}, nil
+	}
+	return conn, nil
+}
+
+// tunnelConn is a connection through a SOCKS5 proxy that reports the address
+// at the far end of the tunnel as its remote address
+type tunnelConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c tunnelConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// dial opens a connection without any SOCKS5 proxy
+func (d countingDialer) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.unixSocket != "" {
+		network, addr = "unix", d.unixSocket
+	}
+
+	dialer := d.Dialer
+	if d.sources != nil {
+		bound := *d.Dialer
+		bound.LocalAddr = d.sources.pick()
+		dialer = &bound
+	}
+
+	markDial(ctx)
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err == nil {
+		countConn(ctx)
+	}
+	return conn, err
+}
+
+// target applies --connect-to, --resolve and address spreading to the
+// address a transport asked for. Hosts without overrides are left for the
+// dialer to resolve unless spreading is enabled.
+func (d countingDialer) target(ctx context.Context, addr string) (string, error) {
+	addr = d.overrides.redirect(addr)
+
+	if d.spread != nil {
+		var err error
+		if addr, err = d.spread.pick(ctx, addr); err != nil {
+			return "", err
+		}
+	} else if ips := d.overrides.lookup(addr); len(ips) > 0 {
+		_, port, _ := net.SplitHostPort(addr)
+		addr = net.JoinHostPort(ips[0].String(), port)
+	}
+
+	markDialedIP(ctx, addr)
+	return addr, nil
+}
+
+// resolve returns addr with its host resolved to a single IP address, after
+// applying the same overrides as DialContext
+func (d countingDialer) resolve(ctx context.Context, addr string) (string, error) {
+	addr, err := d.target(ctx, addr)
+	if err != nil {
+		return "", err
+	}
+	return resolveAddr(ctx, d.Dialer, addr)
+}
+
+// withSOCKS5 returns a copy of d that tunnels connections through the SOCKS5
+// proxy at proxyURL
+func (d countingDialer) withSOCKS5(proxyURL *url.URL) (countingDialer, error) {
+	var auth *proxy.Auth
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		auth = &proxy.Auth{User: proxyURL.User.Username(), Password: password}
+	}
+
+	socks, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, dialFunc(d.dial))
+	if err != nil {
+		return d, err
+	}
+
+	d.socks = socks.(proxy.ContextDialer)
+	d.socksResolve = proxyURL.Scheme == "socks5"
+	return d, nil
+}
+
+// dialFunc adapts a dial function to proxy.Dialer and proxy.ContextDialer
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func (f dialFunc) Dial(network, addr string) (net.Conn, error) {
+	return f(context.Background(), network, addr)
+}
+
+func (f dialFunc) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return f(ctx, network, addr)
+}
@@ -3,5 +3,6 @@ package client
 import "errors"
 
 var (
-	ErrInvalidProxyURL = errors.New("invalid proxy URL")
+	ErrInvalidProxyURL   = errors.New("invalid proxy URL")
+	ErrInvalidCookieFile = errors.New("invalid cookie file. Expected the Netscape format with 7 tab-separated fields per line")
 )
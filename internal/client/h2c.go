@@ -0,0 +1,60 @@
+package client
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+
+	"golang.org/x/net/http2"
+)
+
+// h2cConnPerRequest opens a new h2c connection for every request and closes
+// it once the response body is closed
+type h2cConnPerRequest struct {
+	transport *http2.Transport
+	dialer    countingDialer
+}
+
+func (t *h2cConnPerRequest) RoundTrip(req *http.Request) (*http.Response, error) {
+	addr := req.URL.Host
+	if req.URL.Port() == "" {
+		addr = net.JoinHostPort(req.URL.Hostname(), "80")
+	}
+
+	conn, err := t.dialer.DialContext(req.Context(), "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	cc, err := t.transport.NewClientConn(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if trace := httptrace.ContextClientTrace(req.Context()); trace != nil && trace.GotConn != nil {
+		trace.GotConn(httptrace.GotConnInfo{Conn: conn})
+	}
+
+	resp, err := cc.RoundTrip(req)
+	if err != nil {
+		cc.Close()
+		return nil, err
+	}
+
+	resp.Body = &closeConnBody{ReadCloser: resp.Body, conn: cc}
+	return resp, nil
+}
+
+// closeConnBody closes the underlying connection along with the body
+type closeConnBody struct {
+	io.ReadCloser
+	conn *http2.ClientConn
+}
+
+func (b *closeConnBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.conn.Close()
+	return err
+}
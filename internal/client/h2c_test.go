@@ -0,0 +1,68 @@
+package client
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rnemeth90/yahba/internal/config"
+	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// h2cServer serves HTTP/2 over cleartext and counts accepted connections
+func h2cServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var conns atomic.Int32
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})
+	ts := httptest.NewUnstartedServer(h2c.NewHandler(handler, &http2.Server{}))
+	ts.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	ts.Start()
+	t.Cleanup(ts.Close)
+
+	return ts, &conns
+}
+
+func TestH2CPriorKnowledge(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		reuse bool
+		conns int32
+	}{
+		{"reuse", true, 1},
+		{"connection per request", false, 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ts, conns := h2cServer(t)
+			client, err := NewClient(config.Config{
+				URL:              ts.URL,
+				Timeout:          5,
+				HTTP2:            true,
+				ReuseConnections: tc.reuse,
+				Logger:           logger.New("error", "stdout", false),
+			})
+			assert.NoError(t, err)
+
+			for i := 0; i < 3; i++ {
+				resp, err := client.Get(ts.URL)
+				if !assert.NoError(t, err) {
+					return
+				}
+				resp.Body.Close()
+				assert.Equal(t, "HTTP/2.0", resp.Proto)
+			}
+			assert.Equal(t, tc.conns, conns.Load())
+		})
+	}
+}
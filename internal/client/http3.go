@@ -0,0 +1,44 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+
+	"github.com/quic-go/quic-go"
+)
+
+// dialQUIC opens a QUIC connection and waits for the handshake, reporting it
+// through the request's httptrace hooks
+func dialQUIC(ctx context.Context, addr string, tlsCfg *tls.Config, quicCfg *quic.Config) (quic.EarlyConnection, error) {
+	trace := httptrace.ContextClientTrace(ctx)
+	if trace != nil && trace.TLSHandshakeStart != nil {
+		trace.TLSHandshakeStart()
+	}
+
+	conn, err := quic.DialAddrEarly(ctx, addr, tlsCfg, quicCfg)
+	if err == nil {
+		select {
+		case <-conn.HandshakeComplete():
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+
+	if trace != nil && trace.TLSHandshakeDone != nil {
+		var state tls.ConnectionState
+		if err == nil {
+			state = conn.ConnectionState().TLS
+		}
+		trace.TLSHandshakeDone(state, err)
+	}
+
+	if err != nil {
+		if conn != nil {
+			conn.CloseWithError(0, "")
+		}
+		return nil, err
+	}
+	countConn(ctx)
+	return conn, nil
+}
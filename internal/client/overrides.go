@@ -0,0 +1,82 @@
+package client
+
+import (
+	"net"
+	"strings"
+
+	"github.com/rnemeth90/yahba/internal/config"
+)
+
+// hostOverrides applies --connect-to mappings and --resolve addresses to
+// the addresses transports dial. Only the dialed address changes; requests
+// keep the host name from the URL.
+type hostOverrides struct {
+	resolve   map[string][]net.IPAddr
+	connectTo []config.ConnectTo
+}
+
+// newHostOverrides returns nil when no overrides are configured
+func newHostOverrides(cfg config.Config) (*hostOverrides, error) {
+	resolves, err := config.ParseResolve(cfg.Resolve)
+	if err != nil {
+		return nil, err
+	}
+	connectTo, err := config.ParseConnectTo(cfg.ConnectTo)
+	if err != nil {
+		return nil, err
+	}
+	if len(resolves) == 0 && len(connectTo) == 0 {
+		return nil, nil
+	}
+
+	o := &hostOverrides{resolve: make(map[string][]net.IPAddr), connectTo: connectTo}
+	for _, r := range resolves {
+		key := net.JoinHostPort(r.Host, r.Port)
+		// like curl, the first entry for a host and port wins
+		if _, ok := o.resolve[key]; ok {
+			continue
+		}
+		for _, addr := range r.Addrs {
+			o.resolve[key] = append(o.resolve[key], net.IPAddr{IP: addr.AsSlice(), Zone: addr.Zone()})
+		}
+	}
+	return o, nil
+}
+
+// redirect applies the first --connect-to mapping that matches addr
+func (o *hostOverrides) redirect(addr string) string {
+	if o == nil {
+		return addr
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	for _, m := range o.connectTo {
+		if (m.Host != "" && m.Host != strings.ToLower(host)) || (m.Port != "" && m.Port != port) {
+			continue
+		}
+		if m.ToHost != "" {
+			host = m.ToHost
+		}
+		if m.ToPort != "" {
+			port = m.ToPort
+		}
+		return net.JoinHostPort(host, port)
+	}
+	return addr
+}
+
+// lookup returns the --resolve addresses for addr, or nil when the host is
+// resolved normally
+func (o *hostOverrides) lookup(addr string) []net.IPAddr {
+	if o == nil {
+		return nil
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil
+	}
+	return o.resolve[net.JoinHostPort(strings.ToLower(host), port)]
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rnemeth90/yahba/internal/config"
+	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostOverridesRedirect(t *testing.T) {
+	o, err := newHostOverrides(config.Config{
+		ConnectTo: []string{"example.com:443:canary.internal:", "::backup.internal:8080"},
+		Resolve:   []string{"canary.internal:443:10.0.0.7", "canary.internal:443:10.0.0.8"},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "canary.internal:443", o.redirect("EXAMPLE.com:443"))
+	assert.Equal(t, "backup.internal:8080", o.redirect("other.test:80"))
+	assert.Equal(t, "10.0.0.7", o.lookup("canary.internal:443")[0].String())
+	assert.Len(t, o.lookup("canary.internal:443"), 1)
+	assert.Nil(t, o.lookup("canary.internal:80"))
+
+	o, err = newHostOverrides(config.Config{})
+	assert.NoError(t, err)
+	assert.Nil(t, o)
+	assert.Equal(t, "example.com:443", o.redirect("example.com:443"))
+}
+
+func TestResolveKeepsHostAndSNI(t *testing.T) {
+	var host, serverName string
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, serverName = r.Host, r.TLS.ServerName
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	url := "https://canary.example.com:" + port
+
+	tests := []struct {
+		name      string
+		http2     bool
+		proto     string
+		resolve   []string
+		connectTo []string
+	}{
+		{"resolve HTTP/1.1", false, "HTTP/1.1", []string{"canary.example.com:" + port + ":127.0.0.1"}, nil},
+		{"resolve HTTP/2", true, "HTTP/2.0", []string{"canary.example.com:" + port + ":127.0.0.1"}, nil},
+		{"connect-to HTTP/1.1", false, "HTTP/1.1", nil, []string{"canary.example.com:" + port + ":127.0.0.1:"}},
+		{"connect-to HTTP/2", true, "HTTP/2.0", nil, []string{"::127.0.0.1:" + port}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(config.Config{
+				URL:       url,
+				Timeout:   5,
+				Insecure:  true,
+				HTTP2:     tt.http2,
+				Resolve:   tt.resolve,
+				ConnectTo: tt.connectTo,
+				Logger:    logger.New("error", "stdout", false),
+			})
+			assert.NoError(t, err)
+
+			resp, err := client.Get(url)
+			if !assert.NoError(t, err) {
+				return
+			}
+			resp.Body.Close()
+			assert.Equal(t, tt.proto, resp.Proto)
+			assert.Equal(t, "canary.example.com:"+port, host)
+			assert.Equal(t, "canary.example.com", serverName)
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package client
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/config"
+	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+// socksProxy is a minimal SOCKS5 proxy stand-in. It connects every domain
+// name to 127.0.0.1 and remembers the targets it was asked for.
+type socksProxy struct {
+	addr     string
+	user     string
+	password string
+
+	mu      sync.Mutex
+	targets []string
+}
+
+func newSOCKSProxy(t *testing.T, user, password string) *socksProxy {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	p := &socksProxy{addr: l.Addr().String(), user: user, password: password}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go p.serve(conn)
+		}
+	}()
+	return p
+}
+
+func (p *socksProxy) serve(conn net.Conn) {
+	defer conn.Close()
+
+	// greeting: version, methods
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(conn, hdr); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, make([]byte, hdr[1])); err != nil {
+		return
+	}
+
+	if p.user == "" {
+		conn.Write([]byte{5, 0})
+	} else {
+		conn.Write([]byte{5, 2})
+		if !p.authenticate(conn) {
+			conn.Write([]byte{1, 1})
+			return
+		}
+		conn.Write([]byte{1, 0})
+	}
+
+	// request: version, command, reserved, address type, address, port
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return
+	}
+	var host string
+	switch req[3] {
+	case 1:
+		ip := make([]byte, 4)
+		io.ReadFull(conn, ip)
+		host = net.IP(ip).String()
+	case 3:
+		n := make([]byte, 1)
+		io.ReadFull(conn, n)
+		name := make([]byte, n[0])
+		io.ReadFull(conn, name)
+		host = string(name)
+	default:
+		return
+	}
+	port := make([]byte, 2)
+	io.ReadFull(conn, port)
+	target := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+
+	p.mu.Lock()
+	p.targets = append(p.targets, target)
+	p.mu.Unlock()
+
+	if net.ParseIP(host) == nil {
+		host = "127.0.0.1"
+	}
+	upstream, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))))
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer upstream.Close()
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+
+	go io.Copy(upstream, conn)
+	io.Copy(conn, upstream)
+}
+
+func (p *socksProxy) authenticate(conn net.Conn) bool {
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(conn, hdr); err != nil {
+		return false
+	}
+	user := make([]byte, hdr[1])
+	io.ReadFull(conn, user)
+	n := make([]byte, 1)
+	io.ReadFull(conn, n)
+	password := make([]byte, n[0])
+	io.ReadFull(conn, password)
+	return string(user) == p.user && string(password) == p.password
+}
+
+func (p *socksProxy) seen() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.targets...)
+}
+
+// getWithStats sends a GET request and returns its connection stats
+func getWithStats(t *testing.T, cfg config.Config) (*http.Response, *ConnStats, error) {
+	t.Helper()
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats := &ConnStats{}
+	req, _ := http.NewRequestWithContext(WithConnStats(context.Background(), stats), http.MethodGet, cfg.URL, nil)
+	resp, err := client.Do(req)
+	if err == nil {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	return resp, stats, err
+}
+
+func TestSOCKS5Proxy(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	target := "http://yahba.test:" + port
+
+	t.Run("socks5h leaves resolution to the proxy", func(t *testing.T) {
+		proxy := newSOCKSProxy(t, "user", "secret")
+		_, stats, err := getWithStats(t, config.Config{
+			URL:           target,
+			Timeout:       5,
+			Proxy:         "socks5h://" + proxy.addr,
+			ProxyUser:     "user",
+			ProxyPassword: "secret",
+			Logger:        logger.New("error", "stdout", false),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"yahba.test:" + port}, proxy.seen())
+		assert.Greater(t, stats.ProxyTime(), time.Duration(0))
+		assert.Equal(t, 1, stats.Opened())
+	})
+
+	t.Run("socks5 resolves locally", func(t *testing.T) {
+		proxy := newSOCKSProxy(t, "", "")
+		resolver, _ := dnsServer(t)
+		_, _, err := getWithStats(t, config.Config{
+			URL:      target,
+			Timeout:  5,
+			Proxy:    "socks5://" + proxy.addr,
+			Resolver: resolver,
+			Logger:   logger.New("error", "stdout", false),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"127.0.0.1:" + port}, proxy.seen())
+	})
+
+	t.Run("wrong credentials", func(t *testing.T) {
+		proxy := newSOCKSProxy(t, "user", "secret")
+		_, _, err := getWithStats(t, config.Config{
+			URL:     target,
+			Timeout: 5,
+			Proxy:   "socks5h://user:wrong@" + proxy.addr,
+			Logger:  logger.New("error", "stdout", false),
+		})
+		assert.Error(t, err)
+		assert.Empty(t, proxy.seen())
+	})
+
+	t.Run("h2c", func(t *testing.T) {
+		h2c, _ := h2cServer(t)
+		_, h2cPort, _ := net.SplitHostPort(h2c.Listener.Addr().String())
+		proxy := newSOCKSProxy(t, "", "")
+		resp, _, err := getWithStats(t, config.Config{
+			URL:     "http://yahba.test:" + h2cPort,
+			Timeout: 5,
+			HTTP2:   true,
+			Proxy:   "socks5h://" + proxy.addr,
+			Logger:  logger.New("error", "stdout", false),
+		})
+		if assert.NoError(t, err) {
+			assert.Equal(t, "HTTP/2.0", resp.Proto)
+		}
+	})
+}
+
+func TestConnectProxyTiming(t *testing.T) {
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+	proxy, tunnels := connectProxy(t)
+
+	_, stats, err := getWithStats(t, config.Config{
+		URL:      target.URL,
+		Timeout:  5,
+		Insecure: true,
+		Proxy:    proxy.URL,
+		Logger:   logger.New("error", "stdout", false),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), tunnels.Load())
+	assert.Greater(t, stats.ProxyTime(), time.Duration(0))
+}
+
+func TestSOCKS5ProxyReportsBackend(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+
+	proxy := newSOCKSProxy(t, "", "")
+	resolver, _ := dnsServer(t, [4]byte{127, 0, 0, 1})
+	client, err := NewClient(config.Config{
+		URL:         "http://spread.test:" + port,
+		Timeout:     5,
+		SpreadAddrs: true,
+		Resolver:    resolver,
+		Proxy:       "socks5h://" + proxy.addr,
+		Logger:      logger.New("error", "stdout", false),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the connection's peer is the backend, not the proxy
+	var remote string
+	trace := &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) { remote = info.Conn.RemoteAddr().String() }}
+	req, _ := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), http.MethodGet, "http://spread.test:"+port, nil)
+	resp, err := client.Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	resp.Body.Close()
+	assert.Equal(t, ts.Listener.Addr().String(), remote)
+	assert.Equal(t, []string{"127.0.0.1:" + port}, proxy.seen())
+}
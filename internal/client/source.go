@@ -0,0 +1,39 @@
+package client
+
+import (
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"sync/atomic"
+
+	"github.com/rnemeth90/yahba/internal/config"
+)
+
+// sourceRotator picks the local address each new connection is bound to
+type sourceRotator struct {
+	addrs  []netip.Addr
+	random bool
+	next   atomic.Uint64
+}
+
+// newSourceRotator returns nil when no source addresses are configured
+func newSourceRotator(cfg config.Config) (*sourceRotator, error) {
+	addrs, err := config.ParseSourceAddrs(cfg.SourceAddrs)
+	if err != nil || len(addrs) == 0 {
+		return nil, err
+	}
+
+	cfg.Logger.Debug("Rotating connections across %d source addresses (%s)", len(addrs), cfg.SourceAddrMode)
+	return &sourceRotator{addrs: addrs, random: cfg.SourceAddrMode == config.SourceAddrRandom}, nil
+}
+
+// pick returns the local address for the next connection
+func (s *sourceRotator) pick() *net.TCPAddr {
+	var i int
+	if s.random {
+		i = rand.IntN(len(s.addrs))
+	} else {
+		i = int((s.next.Add(1) - 1) % uint64(len(s.addrs)))
+	}
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(s.addrs[i], 0))
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/rnemeth90/yahba/internal/config"
+	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceAddrRotation(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		mu.Lock()
+		seen[host]++
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(config.Config{
+		URL:               ts.URL,
+		Timeout:           5,
+		NewConnPerRequest: true,
+		SourceAddrs:       "127.0.0.2,127.0.0.3",
+		SourceAddrMode:    config.SourceAddrRoundRobin,
+		Logger:            logger.New("error", "stdout", false),
+	})
+	assert.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		resp, err := client.Get(ts.URL)
+		if !assert.NoError(t, err) {
+			return
+		}
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, map[string]int{"127.0.0.2": 2, "127.0.0.3": 2}, seen)
+}
+
+func TestSourceAddrRandom(t *testing.T) {
+	s, err := newSourceRotator(config.Config{
+		SourceAddrs:    "127.0.0.0/29",
+		SourceAddrMode: config.SourceAddrRandom,
+		Logger:         logger.New("error", "stdout", false),
+	})
+	assert.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		ip := s.pick().IP
+		assert.True(t, ip.IsLoopback() && !ip.Equal(net.IPv4(127, 0, 0, 0)) && !ip.Equal(net.IPv4(127, 0, 0, 7)), "unexpected source %s", ip)
+	}
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/config"
+)
+
+// spreadRefresh is how long a host's addresses are used before it is
+// resolved again. Connections keep going to the old addresses while the
+// new lookup runs.
+var spreadRefresh = time.Minute
+
+// addrSpreader spreads new connections round robin across every address a
+// host resolves to, or every --resolve address given for it. Lookups run
+// outside the lock and are shared by every connection waiting on the same
+// host and port; failed lookups are not cached.
+type addrSpreader struct {
+	resolver  *net.Resolver
+	overrides *hostOverrides
+	cfg       config.Config
+
+	mu    sync.Mutex
+	hosts map[string]*hostAddrs
+}
+
+type hostAddrs struct {
+	ips      []net.IPAddr
+	next     int
+	resolved time.Time
+	// static addresses come from --resolve and are never looked up again
+	static bool
+	// pending is the lookup in flight, if any
+	pending *addrLookup
+}
+
+// addrLookup is closed when a lookup finishes. err is set before done is
+// closed.
+type addrLookup struct {
+	done chan struct{}
+	err  error
+}
+
+// newAddrSpreader returns nil unless spreading is enabled
+func newAddrSpreader(cfg config.Config, dialer *net.Dialer, overrides *hostOverrides) *addrSpreader {
+	if !cfg.SpreadAddrs {
+		return nil
+	}
+
+	resolver := dialer.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &addrSpreader{resolver: resolver, overrides: overrides, cfg: cfg, hosts: make(map[string]*hostAddrs)}
+}
+
+// pick replaces the host in addr with the next of its addresses
+func (s *addrSpreader) pick(ctx context.Context, addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(host) != nil {
+		return addr, nil
+	}
+
+	s.mu.Lock()
+	h, ok := s.hosts[addr]
+	if !ok {
+		h = &hostAddrs{}
+		if ips := s.overrides.lookup(addr); ips != nil {
+			h.ips, h.static = ips, true
+		}
+		s.hosts[addr] = h
+	}
+	if !h.static && h.pending == nil && (h.ips == nil || time.Since(h.resolved) > spreadRefresh) {
+		h.pending = &addrLookup{done: make(chan struct{})}
+		// the lookup outlives this dial's context, another dial may be waiting on it
+		go s.resolve(context.WithoutCancel(ctx), addr, host, h, h.pending)
+	}
+	if h.ips != nil {
+		defer s.mu.Unlock()
+		return h.take(port), nil
+	}
+	l := h.pending
+	s.mu.Unlock()
+
+	select {
+	case <-l.done:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	if l.err != nil {
+		return "", l.err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return h.take(port), nil
+}
+
+// resolve looks host up and installs its addresses in h
+func (s *addrSpreader) resolve(ctx context.Context, addr, host string, h *hostAddrs, l *addrLookup) {
+	ips, err := s.resolver.LookupIPAddr(ctx, host)
+	if err == nil && len(ips) == 0 {
+		err = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	s.mu.Lock()
+	if err != nil {
+		s.cfg.Logger.Debug("Failed to resolve %s for address spreading: %v", host, err)
+	} else {
+		if h.ips == nil {
+			s.cfg.Logger.Debug("Spreading connections to %s across %d addresses: %v", host, len(ips), ips)
+		}
+		h.ips, h.resolved = ips, time.Now()
+	}
+	h.pending = nil
+	l.err = err
+	s.mu.Unlock()
+	close(l.done)
+}
+
+// take returns the next address round robin. The caller holds the lock.
+func (h *hostAddrs) take(port string) string {
+	ip := h.ips[h.next%len(h.ips)]
+	h.next++
+	return net.JoinHostPort(ip.String(), port)
+}
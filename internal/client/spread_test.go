@@ -0,0 +1,166 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/config"
+	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+// spreadServer listens on every loopback address and counts requests by the
+// address they arrived on
+func spreadServer(t *testing.T) (string, func() map[string]int) {
+	t.Helper()
+	ln, err := net.Listen("tcp4", "0.0.0.0:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		local := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+		mu.Lock()
+		seen[local.(*net.TCPAddr).IP.String()]++
+		mu.Unlock()
+	}))
+	ts.Listener.Close()
+	ts.Listener = ln
+	ts.Start()
+	t.Cleanup(ts.Close)
+
+	return strconv.Itoa(ln.Addr().(*net.TCPAddr).Port), func() map[string]int {
+		mu.Lock()
+		defer mu.Unlock()
+		return seen
+	}
+}
+
+func TestSpreadAddrs(t *testing.T) {
+	port, seen := spreadServer(t)
+	resolver, queries := dnsServer(t, [4]byte{127, 0, 0, 1}, [4]byte{127, 0, 0, 2}, [4]byte{127, 0, 0, 3})
+	url := "http://spread.test:" + port
+
+	client, err := NewClient(config.Config{
+		URL:               url,
+		Timeout:           5,
+		NewConnPerRequest: true,
+		SpreadAddrs:       true,
+		Resolver:          resolver,
+		Logger:            logger.New("error", "stdout", false),
+	})
+	assert.NoError(t, err)
+
+	var lookups int32
+	for i := 0; i < 6; i++ {
+		resp, err := client.Get(url)
+		if !assert.NoError(t, err) {
+			return
+		}
+		resp.Body.Close()
+		if i == 0 {
+			lookups = queries.Load()
+		}
+	}
+
+	assert.Equal(t, map[string]int{"127.0.0.1": 2, "127.0.0.2": 2, "127.0.0.3": 2}, seen())
+	// the host is resolved once for the whole run
+	assert.Equal(t, lookups, queries.Load())
+}
+
+func TestSpreadAddrsRecordsDialedIP(t *testing.T) {
+	// nothing listens on the port, so the dial fails
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.Close()
+
+	resolver, _ := dnsServer(t, [4]byte{127, 0, 0, 1})
+	_, stats, err := getWithStats(t, config.Config{
+		URL:         "http://spread.test:" + strconv.Itoa(ln.Addr().(*net.TCPAddr).Port),
+		Timeout:     5,
+		SpreadAddrs: true,
+		Resolver:    resolver,
+		Logger:      logger.New("error", "stdout", false),
+	})
+	assert.Error(t, err)
+	assert.Equal(t, "127.0.0.1", stats.DialedIP())
+}
+
+func TestSpreadAddrsResolvesOutsideLock(t *testing.T) {
+	dialing, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	resolver := &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+		once.Do(func() { close(dialing) })
+		<-release
+		return nil, errors.New("dns down")
+	}}
+	overrides := &hostOverrides{resolve: map[string][]net.IPAddr{"fixed.test:80": {{IP: net.IPv4(127, 0, 0, 9)}}}}
+	s := &addrSpreader{resolver: resolver, overrides: overrides, cfg: config.Config{Logger: logger.New("error", "stdout", false)}, hosts: make(map[string]*hostAddrs)}
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := s.pick(context.Background(), "slow.test:80")
+		errc <- err
+	}()
+	<-dialing
+
+	// other hosts are not held up by a slow lookup
+	addr, err := s.pick(context.Background(), "fixed.test:80")
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.9:80", addr)
+
+	// a dial waiting on the lookup gives up when its own context ends
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = s.pick(ctx, "slow.test:80")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(release)
+	assert.Error(t, <-errc)
+}
+
+func TestSpreadAddrsRetriesLookups(t *testing.T) {
+	dnsAddr, queries := dnsServer(t, [4]byte{127, 0, 0, 1}, [4]byte{127, 0, 0, 2})
+	var down atomic.Bool
+	down.Store(true)
+	resolver := &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+		if down.Load() {
+			return nil, errors.New("dns down")
+		}
+		var d net.Dialer
+		return d.DialContext(ctx, network, dnsAddr)
+	}}
+	s := &addrSpreader{resolver: resolver, cfg: config.Config{Logger: logger.New("error", "stdout", false)}, hosts: make(map[string]*hostAddrs)}
+
+	_, err := s.pick(context.Background(), "spread.test:80")
+	assert.Error(t, err)
+
+	// failures are not cached
+	down.Store(false)
+	for _, expected := range []string{"127.0.0.1:80", "127.0.0.2:80", "127.0.0.1:80"} {
+		addr, err := s.pick(context.Background(), "spread.test:80")
+		assert.NoError(t, err)
+		assert.Equal(t, expected, addr)
+	}
+
+	// stale addresses are used while the host is resolved again
+	defer func(d time.Duration) { spreadRefresh = d }(spreadRefresh)
+	spreadRefresh = 0
+	resolved := queries.Load()
+	addr, err := s.pick(context.Background(), "spread.test:80")
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.2:80", addr)
+	assert.Eventually(t, func() bool { return queries.Load() > resolved }, 5*time.Second, 10*time.Millisecond)
+}
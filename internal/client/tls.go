@@ -0,0 +1,56 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/rnemeth90/yahba/internal/config"
+)
+
+// newTLSConfig builds the TLS settings shared by every transport
+func newTLSConfig(cfg config.Config) (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		InsecureSkipVerify: cfg.Insecure,
+		ServerName:         cfg.SNI,
+	}
+
+	if cfg.CertFile != "" {
+		cfg.Logger.Debug("Loading client certificate from %s", cfg.CertFile)
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if cfg.CACertFile != "" {
+		cfg.Logger.Debug("Loading CA bundle from %s", cfg.CACertFile)
+		pem, err := os.ReadFile(cfg.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading CA bundle: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, config.ErrInvalidCACert
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	var err error
+	if tlsCfg.MinVersion, err = config.ParseTLSVersion(cfg.TLSMinVersion); err != nil {
+		return nil, err
+	}
+	if tlsCfg.MaxVersion, err = config.ParseTLSVersion(cfg.TLSMaxVersion); err != nil {
+		return nil, err
+	}
+
+	// crypto/tls only honours CipherSuites for TLS 1.2 and below
+	if tlsCfg.CipherSuites, err = config.ParseCipherSuites(cfg.Ciphers); err != nil {
+		return nil, err
+	}
+
+	return tlsCfg, nil
+}
@@ -0,0 +1,197 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/config"
+	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	tlsCert tls.Certificate
+}
+
+// issue creates a certificate signed by parent, or self-signed when parent is nil
+func issue(t *testing.T, name string, parent *testCert, isCA bool, usage x509.ExtKeyUsage) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{usage},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		tlsCert: tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key},
+	}
+}
+
+// writePEM writes the certificate and key to dir and returns their paths
+func writePEM(t *testing.T, dir, name string, c *testCert) (string, string) {
+	t.Helper()
+	certPath := filepath.Join(dir, name+".crt")
+	keyPath := filepath.Join(dir, name+".key")
+
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0600))
+	assert.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certPath, keyPath
+}
+
+// mtlsServer starts an HTTPS server for example.test that requires a client
+// certificate signed by the same private CA
+func mtlsServer(t *testing.T, minVersion uint16) (*httptest.Server, config.Config) {
+	t.Helper()
+	dir := t.TempDir()
+
+	ca := issue(t, "Test CA", nil, true, x509.ExtKeyUsageAny)
+	serverCert := issue(t, "example.test", ca, false, x509.ExtKeyUsageServerAuth)
+	clientCert := issue(t, "client", ca, false, x509.ExtKeyUsageClientAuth)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.EnableHTTP2 = true
+	ts.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert.tlsCert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   minVersion,
+	}
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+
+	caPath, _ := writePEM(t, dir, "ca", ca)
+	certPath, keyPath := writePEM(t, dir, "client", clientCert)
+
+	return ts, config.Config{
+		Timeout:    5,
+		CertFile:   certPath,
+		KeyFile:    keyPath,
+		CACertFile: caPath,
+		SNI:        "example.test",
+		Logger:     logger.New("error", "stdout", false),
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	tests := []struct {
+		name  string
+		http2 bool
+		reuse bool
+		proto string
+	}{
+		{"HTTP/1.1", false, false, "HTTP/1.1"},
+		{"HTTP/2", true, false, "HTTP/2.0"},
+		{"HTTP/2 reused connections", true, true, "HTTP/2.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, cfg := mtlsServer(t, 0)
+			cfg.HTTP2 = tt.http2
+			cfg.ReuseConnections = tt.reuse
+
+			client, err := NewClient(cfg)
+			assert.NoError(t, err)
+
+			resp, err := client.Get(ts.URL)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer resp.Body.Close()
+			assert.Equal(t, tt.proto, resp.Proto)
+		})
+	}
+}
+
+func TestMutualTLSWithoutClientCert(t *testing.T) {
+	ts, cfg := mtlsServer(t, 0)
+	cfg.CertFile, cfg.KeyFile = "", ""
+
+	client, err := NewClient(cfg)
+	assert.NoError(t, err)
+
+	_, err = client.Get(ts.URL)
+	assert.Error(t, err)
+}
+
+func TestTLSVersionMismatch(t *testing.T) {
+	ts, cfg := mtlsServer(t, tls.VersionTLS13)
+	cfg.TLSMaxVersion = "1.2"
+
+	client, err := NewClient(cfg)
+	assert.NoError(t, err)
+
+	_, err = client.Get(ts.URL)
+	assert.Error(t, err)
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	cfg := config.Config{
+		Insecure:      true,
+		TLSMinVersion: "1.2",
+		TLSMaxVersion: "1.3",
+		Ciphers:       "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+		SNI:           "example.test",
+		Logger:        logger.New("error", "stdout", false),
+	}
+
+	tlsCfg, err := newTLSConfig(cfg)
+	assert.NoError(t, err)
+	assert.True(t, tlsCfg.InsecureSkipVerify)
+	assert.Equal(t, uint16(tls.VersionTLS12), tlsCfg.MinVersion)
+	assert.Equal(t, uint16(tls.VersionTLS13), tlsCfg.MaxVersion)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}, tlsCfg.CipherSuites)
+	assert.Equal(t, "example.test", tlsCfg.ServerName)
+
+	cfg.CACertFile = filepath.Join(t.TempDir(), "empty.pem")
+	assert.NoError(t, os.WriteFile(cfg.CACertFile, []byte("not a certificate"), 0600))
+	_, err = newTLSConfig(cfg)
+	assert.ErrorIs(t, err, config.ErrInvalidCACert)
+}
@@ -0,0 +1,248 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/rnemeth90/yahba/internal/auth"
+	"github.com/rnemeth90/yahba/internal/config"
+	"golang.org/x/net/http2"
+)
+
+// transportOptions are the settings every protocol's transport is built from
+type transportOptions struct {
+	cfg      config.Config
+	dialer   countingDialer
+	tlsCfg   *tls.Config
+	proxyURL *url.URL
+	timeout  time.Duration
+	// idleTimeout is how long idle connections are kept in the pool
+	idleTimeout time.Duration
+}
+
+// newTransport builds the round tripper for the configured protocol. Every
+// protocol dials through the same dialer, so timeouts, custom resolvers and
+// DNS bypass behave identically; combinations a protocol cannot support are
+// rejected by config.Validate.
+func newTransport(cfg config.Config) (http.RoundTripper, error) {
+	sources, err := newSourceRotator(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides, err := newHostOverrides(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := newDialer(cfg)
+	opts := transportOptions{
+		cfg: cfg,
+		dialer: countingDialer{
+			Dialer:     dialer,
+			sources:    sources,
+			overrides:  overrides,
+			spread:     newAddrSpreader(cfg, dialer, overrides),
+			unixSocket: cfg.UnixSocket,
+		},
+		timeout: time.Duration(cfg.Timeout) * time.Second,
+	}
+	if cfg.IdleConnTimeout > 0 {
+		opts.idleTimeout = cfg.IdleConnTimeout
+	} else {
+		opts.idleTimeout = opts.timeout
+	}
+
+	var proxyURL *url.URL
+	if cfg.Proxy != "" {
+		if proxyURL, err = cfg.SetupProxy(); err != nil {
+			return nil, err
+		}
+
+		// SOCKS5 proxies are dialed like any other connection, so they
+		// work for every protocol that dials TCP
+		if config.IsSOCKSProxy(proxyURL) {
+			if opts.dialer, err = opts.dialer.withSOCKS5(proxyURL); err != nil {
+				return nil, err
+			}
+		} else {
+			opts.proxyURL = proxyURL
+		}
+	}
+
+	if opts.tlsCfg, err = newTLSConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	var transport http.RoundTripper
+	switch {
+	case cfg.HTTP3:
+		transport = newHTTP3Transport(opts)
+	case cfg.HTTP2 && isCleartext(cfg.URL):
+		transport = newH2CTransport(opts)
+	case cfg.HTTP2:
+		if transport, err = newHTTP2Transport(opts); err != nil {
+			return nil, err
+		}
+	default:
+		transport = newHTTP1Transport(opts)
+	}
+
+	if provider := auth.New(cfg, newTokenTransport(opts, proxyURL)); provider != nil {
+		cfg.Logger.Debug("Authenticating requests with %s", cfg.Auth)
+		transport = &auth.Transport{Base: transport, Provider: provider}
+	}
+	return transport, nil
+}
+
+// newTokenTransport returns the transport OAuth2 tokens are fetched with.
+// The token endpoint is not the target, so it only shares the TLS and proxy
+// settings, and none of the protocol, dialing or address overrides.
+func newTokenTransport(opts transportOptions, proxyURL *url.URL) *http.Transport {
+	tlsCfg := opts.tlsCfg.Clone()
+	// --sni names the target
+	tlsCfg.ServerName = ""
+
+	return &http.Transport{
+		Proxy:               http.ProxyURL(proxyURL),
+		DialContext:         (&net.Dialer{Timeout: opts.timeout, KeepAlive: 30 * time.Second}).DialContext,
+		TLSClientConfig:     tlsCfg,
+		TLSHandshakeTimeout: opts.timeout,
+		ForceAttemptHTTP2:   true,
+	}
+}
+
+// newDialer returns the dialer shared by all transports
+func newDialer(cfg config.Config) *net.Dialer {
+	dialer := &net.Dialer{
+		Timeout:   time.Duration(cfg.Timeout) * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	if cfg.SkipDNS {
+		cfg.SkipNameResolution(dialer)
+	}
+
+	if cfg.Resolver != "" {
+		cfg.SetupCustomResolver(dialer)
+	}
+
+	return dialer
+}
+
+// defaultMaxIdleConns is used when the config leaves MaxIdleConns unset
+const defaultMaxIdleConns = 500
+
+func newHTTP1Transport(opts transportOptions) *http.Transport {
+	maxIdle := opts.cfg.MaxIdleConns
+	if maxIdle == 0 {
+		maxIdle = defaultMaxIdleConns
+	}
+
+	return &http.Transport{
+		MaxConnsPerHost:        opts.cfg.MaxConnsPerHost,
+		MaxIdleConns:           maxIdle,
+		MaxIdleConnsPerHost:    maxIdle,
+		IdleConnTimeout:        opts.idleTimeout,
+		TLSHandshakeTimeout:    opts.timeout,
+		DisableKeepAlives:      opts.cfg.KeepAlive || opts.cfg.NewConnPerRequest,
+		DisableCompression:     opts.cfg.Compression,
+		ForceAttemptHTTP2:      false,
+		TLSClientConfig:        opts.tlsCfg,
+		Proxy:                  http.ProxyURL(opts.proxyURL),
+		OnProxyConnectResponse: onProxyConnectResponse,
+		DialContext:            opts.dialer.DialContext,
+	}
+}
+
+// newHTTP2Transport negotiates HTTP/2 over TLS through an http.Transport, so
+// proxies and dialing work as they do for HTTP/1.1. Connections are only
+// multiplexed across requests with --reuse-connections.
+func newHTTP2Transport(opts transportOptions) (*http.Transport, error) {
+	tr := newHTTP1Transport(opts)
+	tr.ForceAttemptHTTP2 = true
+	tr.DisableKeepAlives = !opts.cfg.ReuseConnections || opts.cfg.NewConnPerRequest
+	if err := http2.ConfigureTransport(tr); err != nil {
+		return nil, err
+	}
+	return tr, nil
+}
+
+// newH2CTransport speaks HTTP/2 over plain TCP with prior knowledge, for
+// http:// URLs used with --http2. Connections are multiplexed across requests
+// only when --reuse-connections is set.
+func newH2CTransport(opts transportOptions) http.RoundTripper {
+	transport := &http2.Transport{
+		AllowHTTP:          true,
+		DisableCompression: opts.cfg.Compression,
+		IdleConnTimeout:    opts.idleTimeout,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return opts.dialer.DialContext(ctx, network, addr)
+		},
+	}
+
+	if opts.cfg.ReuseConnections && !opts.cfg.NewConnPerRequest {
+		return transport
+	}
+	return &h2cConnPerRequest{transport: transport, dialer: opts.dialer}
+}
+
+// newHTTP3Transport builds an HTTP/3 round tripper. Host names are resolved
+// with the shared dialer's resolver before dialing QUIC. QUIC has no separate
+// TCP connect, so the whole QUIC handshake is reported through the request's
+// httptrace TLSHandshakeStart and TLSHandshakeDone hooks.
+func newHTTP3Transport(opts transportOptions) *http3.Transport {
+	return &http3.Transport{
+		TLSClientConfig:    opts.tlsCfg,
+		DisableCompression: opts.cfg.Compression,
+		QUICConfig: &quic.Config{
+			HandshakeIdleTimeout: opts.timeout,
+			MaxIdleTimeout:       opts.idleTimeout,
+		},
+		Dial: func(ctx context.Context, addr string, tlsCfg *tls.Config, quicCfg *quic.Config) (quic.EarlyConnection, error) {
+			addr, err := opts.dialer.resolve(ctx, addr)
+			if err != nil {
+				return nil, err
+			}
+			markDialedIP(ctx, addr)
+			return dialQUIC(ctx, addr, tlsCfg, quicCfg)
+		},
+	}
+}
+
+// resolveAddr resolves the host in addr with the dialer's resolver
+func resolveAddr(ctx context.Context, dialer *net.Dialer, addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(host) != nil {
+		return addr, nil
+	}
+
+	resolver := dialer.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	ips, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	if len(ips) == 0 {
+		return "", &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	return net.JoinHostPort(ips[0].IP.String(), port), nil
+}
+
+// isCleartext reports whether rawURL is a plain http:// URL
+func isCleartext(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && u.Scheme == "http"
+}
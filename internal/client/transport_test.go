@@ -0,0 +1,257 @@
+package client
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+	"github.com/rnemeth90/yahba/internal/config"
+	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsServer answers every A query with addrs, 127.0.0.1 by default, and
+// counts the queries
+func dnsServer(t *testing.T, addrs ...[4]byte) (string, *atomic.Int32) {
+	t.Helper()
+	if len(addrs) == 0 {
+		addrs = [][4]byte{{127, 0, 0, 1}}
+	}
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+
+	var queries atomic.Int32
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			var msg dnsmessage.Message
+			if err := msg.Unpack(buf[:n]); err != nil || len(msg.Questions) == 0 {
+				continue
+			}
+			queries.Add(1)
+
+			msg.Header.Response = true
+			msg.Header.Authoritative = true
+			q := msg.Questions[0]
+			if q.Type == dnsmessage.TypeA {
+				for _, a := range addrs {
+					msg.Answers = append(msg.Answers, dnsmessage.Resource{
+						Header: dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: q.Class, TTL: 60},
+						Body:   &dnsmessage.AResource{A: a},
+					})
+				}
+			}
+
+			out, err := msg.Pack()
+			if err == nil {
+				pc.WriteTo(out, addr)
+			}
+		}
+	}()
+
+	return pc.LocalAddr().String(), &queries
+}
+
+// http3Server serves HTTP/3 on a random local UDP port and returns the port
+func http3Server(t *testing.T) int {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert := issue(t, "yahba.test", nil, false, 0)
+	srv := &http3.Server{
+		Handler:   http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		TLSConfig: http3.ConfigureTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert.tlsCert}}),
+	}
+	go srv.Serve(pc)
+	t.Cleanup(func() { srv.Close() })
+
+	return pc.LocalAddr().(*net.UDPAddr).Port
+}
+
+func TestCustomResolverForEveryProtocol(t *testing.T) {
+	http1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer http1.Close()
+	http2 := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	http2.EnableHTTP2 = true
+	http2.StartTLS()
+	defer http2.Close()
+	h2c, _ := h2cServer(t)
+
+	port := func(ts *httptest.Server) string {
+		return strconv.Itoa(ts.Listener.Addr().(*net.TCPAddr).Port)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		url   string
+		http2 bool
+		http3 bool
+		proto string
+	}{
+		{"HTTP/1.1", "http://yahba.test:" + port(http1), false, false, "HTTP/1.1"},
+		{"HTTP/2", "https://yahba.test:" + port(http2), true, false, "HTTP/2.0"},
+		{"h2c", "http://yahba.test:" + port(h2c), true, false, "HTTP/2.0"},
+		{"HTTP/3", "https://yahba.test:" + strconv.Itoa(http3Server(t)), false, true, "HTTP/3.0"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resolver, queries := dnsServer(t)
+			client, err := NewClient(config.Config{
+				URL:      tc.url,
+				Timeout:  5,
+				Insecure: true,
+				HTTP2:    tc.http2,
+				HTTP3:    tc.http3,
+				Resolver: resolver,
+				Logger:   logger.New("error", "stdout", false),
+			})
+			assert.NoError(t, err)
+
+			resp, err := client.Get(tc.url)
+			if !assert.NoError(t, err) {
+				return
+			}
+			resp.Body.Close()
+			assert.Equal(t, tc.proto, resp.Proto)
+			assert.Greater(t, queries.Load(), int32(0))
+		})
+	}
+}
+
+// connectProxy is a minimal HTTP CONNECT proxy that counts tunnels
+func connectProxy(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var tunnels atomic.Int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		tunnels.Add(1)
+
+		w.WriteHeader(http.StatusOK)
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		go func() {
+			io.Copy(upstream, conn)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts, &tunnels
+}
+
+func TestHTTP2ThroughProxy(t *testing.T) {
+	target := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	target.EnableHTTP2 = true
+	target.StartTLS()
+	defer target.Close()
+	proxy, tunnels := connectProxy(t)
+
+	client, err := NewClient(config.Config{
+		URL:      target.URL,
+		Timeout:  5,
+		Insecure: true,
+		HTTP2:    true,
+		Proxy:    proxy.URL,
+		Logger:   logger.New("error", "stdout", false),
+	})
+	assert.NoError(t, err)
+
+	resp, err := client.Get(target.URL)
+	if !assert.NoError(t, err) {
+		return
+	}
+	resp.Body.Close()
+	assert.Equal(t, "HTTP/2.0", resp.Proto)
+	assert.Equal(t, int32(1), tunnels.Load())
+}
+
+func TestConnectionPoolOptions(t *testing.T) {
+	client, err := NewClient(config.Config{
+		URL:               "http://localhost",
+		Timeout:           5,
+		MaxConnsPerHost:   8,
+		MaxIdleConns:      4,
+		IdleConnTimeout:   time.Minute,
+		NewConnPerRequest: true,
+		Logger:            logger.New("error", "stdout", false),
+	})
+	assert.NoError(t, err)
+
+	tr := client.Transport.(*http.Transport)
+	assert.Equal(t, 8, tr.MaxConnsPerHost)
+	assert.Equal(t, 4, tr.MaxIdleConnsPerHost)
+	assert.Equal(t, time.Minute, tr.IdleConnTimeout)
+	assert.True(t, tr.DisableKeepAlives)
+
+	client, err = NewClient(config.Config{URL: "http://localhost", Timeout: 5, Logger: logger.New("error", "stdout", false)})
+	assert.NoError(t, err)
+	tr = client.Transport.(*http.Transport)
+	assert.Equal(t, defaultMaxIdleConns, tr.MaxIdleConnsPerHost)
+	assert.Equal(t, 5*time.Second, tr.IdleConnTimeout)
+}
+
+func TestOAuth2TokensSkipTargetOverrides(t *testing.T) {
+	var fetches atomic.Int32
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write([]byte(`{"access_token":"abc","expires_in":300}`))
+	}))
+	defer idp.Close()
+
+	// the target is reached over h2c on a Unix socket, while the token
+	// endpoint is a plain HTTP/1.1 server on TCP
+	client, err := NewClient(config.Config{
+		URL:            "http://api.internal/v1/items",
+		UnixSocket:     unixServer(t),
+		HTTP2:          true,
+		Timeout:        5,
+		Auth:           "oauth2",
+		OAuth2TokenURL: idp.URL + "/token",
+		OAuth2ClientID: "yahba",
+		Logger:         logger.New("error", "stdout", false),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Get("http://api.internal/v1/items")
+	if !assert.NoError(t, err) {
+		return
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "HTTP/2.0 api.internal/v1/items", string(body))
+	assert.Equal(t, int32(1), fetches.Load())
+}
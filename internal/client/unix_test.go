@@ -0,0 +1,64 @@
+package client
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/rnemeth90/yahba/internal/config"
+	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// unixServer serves on a Unix socket, echoing the Host header and path
+func unixServer(t *testing.T) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "yahba.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto + " " + r.Host + r.URL.RequestURI()))
+	})
+	ts := httptest.NewUnstartedServer(h2c.NewHandler(handler, &http2.Server{}))
+	ts.Listener = l
+	ts.Start()
+	t.Cleanup(ts.Close)
+
+	return socket
+}
+
+func TestUnixSocket(t *testing.T) {
+	socket := unixServer(t)
+
+	for _, http2 := range []bool{false, true} {
+		client, err := NewClient(config.Config{
+			URL:        "http://api.internal/v1/items",
+			UnixSocket: socket,
+			Timeout:    5,
+			HTTP2:      http2,
+			Logger:     logger.New("error", "stdout", false),
+		})
+		assert.NoError(t, err)
+
+		resp, err := client.Get("http://api.internal/v1/items?page=2")
+		if !assert.NoError(t, err) {
+			return
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		expected := "HTTP/1.1 api.internal/v1/items?page=2"
+		if http2 {
+			expected = "HTTP/2.0 api.internal/v1/items?page=2"
+		}
+		assert.Equal(t, expected, string(body))
+	}
+}
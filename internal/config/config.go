@@ -5,10 +5,13 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/rnemeth90/yahba/internal/report"
 	"github.com/rnemeth90/yahba/internal/util"
 )
 
@@ -27,7 +30,7 @@ type Config struct {
 	HTTP2            bool
 	HTTP3            bool
 	LogLevel         string
-	Logger           *logger.Logger
+	Logger           *logger.Logger `json:"-"`
 	RawOutput        bool
 	Compression      bool
 	Proxy            string
@@ -42,6 +45,141 @@ type Config struct {
 	Silent           bool
 	Server           bool
 	ReuseConnections bool
+	Agents           string
+	// AgentToken authenticates the coordinator to its agents and
+	// AgentCACertFile verifies agents served over https. Neither is sent to
+	// the agents.
+	AgentToken       string `json:"-"`
+	AgentCACertFile  string `json:"-"`
+	VerifyServer     bool
+	MaxAttempts      int
+	RetryStatusCodes string
+	RetryErrors      string
+	RetryBackoff     time.Duration
+	RetryMaxBackoff  time.Duration
+	CertFile         string
+	KeyFile          string
+	CACertFile       string
+	TLSMinVersion    string
+	TLSMaxVersion    string
+	Ciphers          string
+	SNI              string
+
+	// Connection pool settings. Zero MaxConnsPerHost means no limit, and zero
+	// MaxIdleConns and IdleConnTimeout use the defaults.
+	MaxConnsPerHost   int
+	MaxIdleConns      int
+	IdleConnTimeout   time.Duration
+	NewConnPerRequest bool
+
+	// SourceAddrs lists local IPs and CIDR ranges that new connections are
+	// bound to, picked per connection according to SourceAddrMode
+	SourceAddrs    string
+	SourceAddrMode string
+
+	// Resolve and ConnectTo override where connections for a host go while
+	// the URL, Host header and SNI keep the host name; see ParseResolve and
+	// ParseConnectTo for the formats
+	Resolve   []string
+	ConnectTo []string
+
+	// SpreadAddrs resolves every address of the target host and spreads new
+	// connections across them instead of letting the dialer pick one
+	SpreadAddrs bool
+
+	// UnixSocket sends every request over this Unix domain socket while the
+	// Host header and path still come from URL
+	UnixSocket string
+
+	// Sessions gives every worker its own cookie jar, seeded with
+	// ParsedCookies, which are read from CookieFile
+	Sessions      bool
+	CookieFile    string
+	ParsedCookies []*http.Cookie
+
+	// Auth is the authentication scheme: basic, digest, bearer or oauth2
+	Auth               string
+	AuthUser           string
+	AuthPassword       string
+	AuthToken          string
+	OAuth2TokenURL     string
+	OAuth2ClientID     string
+	OAuth2ClientSecret string
+	// OAuth2Scopes is a comma-separated list of scopes to request
+	OAuth2Scopes string
+
+	// Sign is the request signing algorithm: hmac-sha256, hmac-sha512 or
+	// aws-sigv4. HMAC secrets come from SignSecretEnv or SignSecretFile; AWS
+	// credentials from the environment or the shared credentials file.
+	Sign                string
+	SignSecretEnv       string
+	SignSecretFile      string
+	SignHeader          string
+	SignTimestampHeader string
+	AWSRegion           string
+	AWSService          string
+	AWSProfile          string
+	AWSCredentialsFile  string
+}
+
+// secretHeaders are the request headers that carry credentials
+var secretHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// HasSecrets reports whether the config carries credentials: passwords,
+// tokens, client secrets, proxy credentials, cookies or credential headers
+func (c *Config) HasSecrets() bool {
+	if c.AuthPassword != "" || c.AuthToken != "" || c.OAuth2ClientSecret != "" || c.ProxyPassword != "" {
+		return true
+	}
+
+	if u, err := url.Parse(c.Proxy); err == nil && u.User != nil {
+		return true
+	}
+
+	if len(c.ParsedCookies) > 0 {
+		return true
+	}
+
+	headers := c.ParsedHeaders
+	if parsed, err := util.ParseHeaders(c.Headers); err == nil {
+		headers = slices.Concat(headers, parsed)
+	}
+	for _, h := range headers {
+		for _, name := range secretHeaders {
+			if strings.EqualFold(strings.TrimSpace(h.Key), name) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// HostLocal returns the flags set in the config that refer to this host:
+// files, environment variables and sockets, including the credentials
+// request signing reads from them. They mean something else, or nothing, on
+// another host, so tests using them cannot be run by agents.
+func (c *Config) HostLocal() []string {
+	var flags []string
+	for _, f := range []struct {
+		flag string
+		set  bool
+	}{
+		{"--cert", c.CertFile != ""},
+		{"--key", c.KeyFile != ""},
+		{"--cacert", c.CACertFile != ""},
+		{"--unix-socket", c.UnixSocket != ""},
+		{"--sign", c.Sign != ""},
+		{"--sign-secret-env", c.SignSecretEnv != ""},
+		{"--sign-secret-file", c.SignSecretFile != ""},
+		{"--aws-profile", c.AWSProfile != ""},
+		{"--aws-credentials-file", c.AWSCredentialsFile != ""},
+	} {
+		if f.set {
+			flags = append(flags, f.flag)
+		}
+	}
+	return flags
 }
 
 var validHTTPMethods = []string{"GET", "HEAD", "PUT", "POST"}
@@ -52,13 +190,12 @@ func (config *Config) Validate() error {
 		return ErrMissingHost
 	}
 
-	if config.OutputFormat == "file" && config.FileName == "" {
-		return ErrInvalidLogFilePath
+	if err := config.splitUnixURL(); err != nil {
+		return err
 	}
 
-	ipAddy := net.ParseIP(config.URL)
-	if config.SkipDNS && ipAddy == nil {
-		return ErrInvalidIPAddressForHost
+	if config.OutputFormat == "file" && config.FileName == "" {
+		return ErrInvalidLogFilePath
 	}
 
 	if !strings.HasPrefix(config.URL, "http") {
@@ -70,7 +207,11 @@ func (config *Config) Validate() error {
 		return ErrInvalidHost
 	}
 
-	if u.Scheme == "https" && config.Insecure {
+	if config.SkipDNS && net.ParseIP(u.Hostname()) == nil {
+		return ErrInvalidIPAddressForHost
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
 		return ErrInvalidProtocolScheme
 	}
 
@@ -90,13 +231,23 @@ func (config *Config) Validate() error {
 		return ErrInvalidTimeout
 	}
 
+	// requests through HTTP proxies are sent by host name, while SOCKS5
+	// proxies are dialed with whatever address yahba picked
+	var httpProxy bool
 	if config.Proxy != "" {
-		if _, err := url.Parse(config.Proxy); err != nil {
+		proxyURL, err := url.Parse(config.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return ErrInvalidProxy
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
 			return ErrInvalidProxy
 		}
 		if (config.ProxyUser == "" && config.ProxyPassword != "") || (config.ProxyUser != "" && config.ProxyPassword == "") {
 			return ErrInvalidProxyAuth
 		}
+		httpProxy = !IsSOCKSProxy(proxyURL)
 	}
 
 	if config.SkipDNS && config.Resolver != "" {
@@ -135,9 +286,200 @@ func (config *Config) Validate() error {
 		return ErrInvalidHTTPConfig
 	}
 
+	if config.HTTP3 && u.Scheme != "https" {
+		return ErrHTTP3RequiresTLS
+	}
+
+	if config.HTTP3 && config.Proxy != "" {
+		return ErrHTTP3Proxy
+	}
+
+	if config.HTTP2 && u.Scheme == "http" && httpProxy {
+		return ErrH2CProxy
+	}
+
+	if config.MaxConnsPerHost < 0 || config.MaxIdleConns < 0 || config.IdleConnTimeout < 0 {
+		return ErrInvalidConnectionPool
+	}
+
+	if config.HTTP3 && config.NewConnPerRequest {
+		return ErrHTTP3NewConnPerRequest
+	}
+
+	if _, err := ParseSourceAddrs(config.SourceAddrs); err != nil {
+		return err
+	}
+
+	if config.SourceAddrMode != "" && config.SourceAddrMode != SourceAddrRoundRobin && config.SourceAddrMode != SourceAddrRandom {
+		return ErrInvalidSourceAddrMode
+	}
+
+	if config.HTTP3 && config.SourceAddrs != "" {
+		return ErrHTTP3SourceAddrs
+	}
+
+	// the target is resolved locally, so only proxies that are given an IP
+	// address can be used
+	if config.SpreadAddrs && httpProxy {
+		return ErrSpreadAddrsProxy
+	}
+
+	if _, err := ParseResolve(config.Resolve); err != nil {
+		return err
+	}
+
+	if _, err := ParseConnectTo(config.ConnectTo); err != nil {
+		return err
+	}
+
+	hostOverrides := len(config.Resolve) > 0 || len(config.ConnectTo) > 0
+	if hostOverrides && httpProxy {
+		return ErrHostOverridesProxy
+	}
+
+	if err := config.validateAuth(); err != nil {
+		return err
+	}
+
+	if err := config.validateSigning(); err != nil {
+		return err
+	}
+
+	if config.UnixSocket != "" && (config.Proxy != "" || config.HTTP3 || config.SourceAddrs != "" || config.SkipDNS || config.Resolver != "" || config.SpreadAddrs || hostOverrides) {
+		return ErrConflictingUnixSocketOptions
+	}
+
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return ErrInvalidClientCert
+	}
+
+	minVersion, err := ParseTLSVersion(config.TLSMinVersion)
+	if err != nil {
+		return err
+	}
+	maxVersion, err := ParseTLSVersion(config.TLSMaxVersion)
+	if err != nil {
+		return err
+	}
+	if minVersion != 0 && maxVersion != 0 && minVersion > maxVersion {
+		return ErrInvalidTLSVersion
+	}
+
+	if _, err := ParseCipherSuites(config.Ciphers); err != nil {
+		return err
+	}
+
+	if config.MaxAttempts < 0 || config.RetryBackoff < 0 || config.RetryMaxBackoff < 0 {
+		return ErrInvalidRetryPolicy
+	}
+
+	if _, err := ParseStatusCodes(config.RetryStatusCodes); err != nil {
+		return err
+	}
+
+	if config.RetryErrors != "" {
+		for _, class := range strings.Split(config.RetryErrors, ",") {
+			if !slices.Contains(report.ErrorClasses, strings.TrimSpace(class)) {
+				return ErrInvalidRetryErrors
+			}
+		}
+	}
+
 	return nil
 }
 
+// ParseStatusCodes parses a comma-separated list of HTTP status codes
+func ParseStatusCodes(raw string) ([]int, error) {
+	var codes []int
+	if strings.TrimSpace(raw) == "" {
+		return codes, nil
+	}
+
+	for _, c := range strings.Split(raw, ",") {
+		code, err := strconv.Atoi(strings.TrimSpace(c))
+		if err != nil || code < 100 || code > 599 {
+			return nil, ErrInvalidStatusCodes
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// validateAuth checks the credentials required by the auth scheme
+func (c *Config) validateAuth() error {
+	switch c.Auth {
+	case "":
+		return nil
+	case "basic", "digest":
+		if c.AuthUser == "" {
+			return ErrMissingAuthCredentials
+		}
+	case "bearer":
+		if c.AuthToken == "" {
+			return ErrMissingAuthCredentials
+		}
+	case "oauth2":
+		u, err := url.Parse(c.OAuth2TokenURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || c.OAuth2ClientID == "" {
+			return ErrMissingAuthCredentials
+		}
+	default:
+		return ErrInvalidAuth
+	}
+	return nil
+}
+
+// validateSigning checks the settings required by the signing algorithm
+func (c *Config) validateSigning() error {
+	switch c.Sign {
+	case "":
+		return nil
+	case "hmac-sha256", "hmac-sha512":
+		if (c.SignSecretEnv == "") == (c.SignSecretFile == "") {
+			return ErrMissingSigningSecret
+		}
+	case "aws-sigv4":
+		if c.AWSRegion == "" || c.AWSService == "" {
+			return ErrMissingAWSScope
+		}
+		if c.Auth != "" {
+			return ErrConflictingSigning
+		}
+	default:
+		return ErrInvalidSigning
+	}
+	return nil
+}
+
+// unixURLPrefix starts the unix:///path/to.sock:/request/path URL form
+const unixURLPrefix = "unix://"
+
+// splitUnixURL rewrites a unix:///path/to.sock:/request/path URL into
+// UnixSocket and an http://localhost URL for the request path
+func (c *Config) splitUnixURL() error {
+	if !strings.HasPrefix(c.URL, unixURLPrefix) {
+		return nil
+	}
+
+	socket, path, _ := strings.Cut(strings.TrimPrefix(c.URL, unixURLPrefix), ":")
+	if socket == "" || (c.UnixSocket != "" && c.UnixSocket != socket) {
+		return ErrInvalidUnixURL
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	c.UnixSocket = socket
+	c.URL = "http://localhost" + path
+	return nil
+}
+
+// IsSOCKSProxy reports whether proxyURL is a socks5:// or socks5h:// proxy
+func IsSOCKSProxy(proxyURL *url.URL) bool {
+	return proxyURL.Scheme == "socks5" || proxyURL.Scheme == "socks5h"
+}
+
 // SetupProxy configures the proxy settings for the client
 func (c *Config) SetupProxy() (*url.URL, error) {
 	c.Logger.Debug("Configuring proxy: %s", c.Proxy)
@@ -155,41 +497,30 @@ func (c *Config) SetupProxy() (*url.URL, error) {
 	return proxyURL, nil
 }
 
-// SkipNameResolution bypasses DNS resolution for the host
-func (c *Config) SkipNameResolution(tr *http.Transport) {
+// SkipNameResolution makes the dialer refuse DNS lookups, so only the IP
+// address in the URL is ever dialed
+func (c *Config) SkipNameResolution(d *net.Dialer) {
 	c.Logger.Debug("Bypassing name resolution for host: %s", c.URL)
-	tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
-		_, port, err := net.SplitHostPort(addr)
-		if err != nil {
-			if strings.HasPrefix(c.URL, "https://") {
-				port = "443"
-			} else {
-				port = "80"
-			}
-		}
-
-		c.Logger.Debug("Bypassing DNS resolution for host: %s:%s", c.URL, port)
-		return net.Dial(network, net.JoinHostPort(c.URL, port))
+	d.Resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return nil, ErrNameResolutionSkipped
+		},
 	}
 }
 
-// SetupCustomResolver configures a custom DNS resolver for the client
-func (c *Config) SetupCustomResolver(tr *http.Transport) {
+// SetupCustomResolver makes the dialer resolve host names with c.Resolver
+func (c *Config) SetupCustomResolver(d *net.Dialer) {
 	c.Logger.Debug("Configuring custom DNS resolver: %s", c.Resolver)
-	tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
-		dialer := &net.Dialer{
-			Resolver: &net.Resolver{
-				PreferGo: true,
-				Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-					c.Logger.Debug("Using custom resolver %s to resolve: %s", c.Resolver, c.URL)
-					d := net.Dialer{
-						Timeout: time.Duration(c.Timeout) * time.Second,
-					}
-
-					return d.DialContext(ctx, "udp", c.Resolver)
-				},
-			},
-		}
-		return dialer.DialContext(ctx, network, addr)
+	d.Resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			c.Logger.Debug("Using custom resolver %s to resolve: %s", c.Resolver, c.URL)
+			rd := net.Dialer{
+				Timeout: time.Duration(c.Timeout) * time.Second,
+			}
+
+			return rd.DialContext(ctx, network, c.Resolver)
+		},
 	}
 }
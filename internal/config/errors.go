@@ -1,26 +1,58 @@
 package config
 
-import "errors"
+import (
+	"errors"
+	"strings"
+
+	"github.com/rnemeth90/yahba/internal/report"
+)
 
 var (
-	ErrMissingHost             = errors.New("URL is required, please specify it using --url or -u")
-	ErrInvalidMethod           = errors.New("invalid HTTP method. Supported methods are GET, POST, PUT, DELETE, etc.")
-	ErrMissingBody             = errors.New("payload is required when using POST or PUT methods")
-	ErrInvalidConcurrency      = errors.New("concurrency must be greater than 0")
-	ErrInvalidRequests         = errors.New("requests must be greater than 0")
-	ErrInvalidTimeout          = errors.New("timeout must be greater than 0")
-	ErrInvalidRPS              = errors.New("requests per second (RPS) must be greater than 0")
-	ErrInvalidOutputFormat     = errors.New("invalid output format. Supported formats are json, yaml, raw")
-	ErrInvalidProxy            = errors.New("invalid proxy server address")
-	ErrInvalidResolvers        = errors.New("invalid DNS resolvers format. Expected a comma-separated list")
-	ErrInvalidHeaders          = errors.New("invalid headers format. Expected a semi-colon separated list of 'Key: Value' pairs")
-	ErrHTTP2Disabled           = errors.New("HTTP/2 is disabled")
-	ErrHTTP3Disabled           = errors.New("hTTP/3 is disabled")
-	ErrInvalidHTTPConfig       = errors.New("invalid HTTP config. Only one value can be supplied")
-	ErrInvalidHost             = errors.New("invalid host")
-	ErrInvalidProtocolScheme   = errors.New("invalid protocol scheme")
-	ErrInvalidProxyAuth        = errors.New("proxy username and password must both be provided")
-	ErrConflictingDNSOptions   = errors.New("cannot use both SkipDNS and custom Resolver")
-	ErrInvalidLogFilePath      = errors.New("you must specify a log file name when writing logs to a file")
-	ErrInvalidIPAddressForHost = errors.New("you chose to skip DNS resolution, but the URL provided does not contain an IP address")
+	ErrMissingHost                  = errors.New("URL is required, please specify it using --url or -u")
+	ErrInvalidMethod                = errors.New("invalid HTTP method. Supported methods are GET, POST, PUT, DELETE, etc.")
+	ErrMissingBody                  = errors.New("payload is required when using POST or PUT methods")
+	ErrInvalidConcurrency           = errors.New("concurrency must be greater than 0")
+	ErrInvalidRequests              = errors.New("requests must be greater than 0")
+	ErrInvalidTimeout               = errors.New("timeout must be greater than 0")
+	ErrInvalidRPS                   = errors.New("requests per second (RPS) must be greater than 0")
+	ErrInvalidOutputFormat          = errors.New("invalid output format. Supported formats are json, yaml, raw")
+	ErrInvalidProxy                 = errors.New("invalid proxy server address")
+	ErrInvalidResolvers             = errors.New("invalid DNS resolvers format. Expected a comma-separated list")
+	ErrInvalidHeaders               = errors.New("invalid headers format. Expected a semi-colon separated list of 'Key: Value' pairs")
+	ErrHTTP2Disabled                = errors.New("HTTP/2 is disabled")
+	ErrInvalidHTTPConfig            = errors.New("invalid HTTP config. Only one value can be supplied")
+	ErrInvalidHost                  = errors.New("invalid host")
+	ErrInvalidProtocolScheme        = errors.New("invalid protocol scheme")
+	ErrInvalidProxyAuth             = errors.New("proxy username and password must both be provided")
+	ErrConflictingDNSOptions        = errors.New("cannot use both SkipDNS and custom Resolver")
+	ErrInvalidLogFilePath           = errors.New("you must specify a log file name when writing logs to a file")
+	ErrInvalidIPAddressForHost      = errors.New("you chose to skip DNS resolution, but the URL provided does not contain an IP address")
+	ErrHTTP3RequiresTLS             = errors.New("HTTP/3 requires an https URL")
+	ErrHTTP3Proxy                   = errors.New("HTTP/3 cannot be used with a proxy")
+	ErrH2CProxy                     = errors.New("HTTP/2 over cleartext (h2c) cannot be used with an HTTP proxy. Use an https URL or a SOCKS5 proxy to tunnel HTTP/2")
+	ErrNameResolutionSkipped        = errors.New("name resolution is disabled by --skip-dns")
+	ErrInvalidConnectionPool        = errors.New("max connections per host, max idle connections and idle timeout must not be negative")
+	ErrHTTP3NewConnPerRequest       = errors.New("a new connection per request is not supported with HTTP/3")
+	ErrInvalidSourceAddrs           = errors.New("invalid source addresses. Expected a comma-separated list of IP addresses and CIDR ranges of at most 65536 addresses")
+	ErrInvalidSourceAddrMode        = errors.New("invalid source address mode. Supported modes are round-robin, random")
+	ErrHTTP3SourceAddrs             = errors.New("source addresses are not supported with HTTP/3")
+	ErrInvalidUnixURL               = errors.New("invalid unix URL. Expected unix:///path/to.sock:/request/path")
+	ErrConflictingUnixSocketOptions = errors.New("a Unix socket cannot be combined with a proxy, HTTP/3, source addresses or DNS options")
+	ErrInvalidAuth                  = errors.New("invalid auth scheme. Supported schemes are basic, digest, bearer, oauth2")
+	ErrMissingAuthCredentials       = errors.New("missing credentials. basic and digest need --auth-user, bearer needs --auth-token and oauth2 needs --oauth2-token-url and --oauth2-client-id")
+	ErrSpreadAddrsProxy             = errors.New("spreading across resolved addresses only works without a proxy or with a SOCKS5 proxy")
+	ErrInvalidResolve               = errors.New("invalid --resolve entry. Use host:port:addr[,addr...]")
+	ErrInvalidConnectTo             = errors.New("invalid --connect-to entry. Use HOST1:PORT1:HOST2:PORT2")
+	ErrHostOverridesProxy           = errors.New("--resolve and --connect-to only work without a proxy or with a SOCKS5 proxy")
+	ErrInvalidSigning               = errors.New("invalid signing algorithm. Supported algorithms are hmac-sha256, hmac-sha512, aws-sigv4")
+	ErrMissingSigningSecret         = errors.New("HMAC signing needs exactly one of --sign-secret-env and --sign-secret-file")
+	ErrMissingAWSScope              = errors.New("AWS SigV4 signing needs --aws-region and --aws-service")
+	ErrConflictingSigning           = errors.New("AWS SigV4 signing sets the Authorization header and cannot be combined with --auth")
+	ErrInvalidClientCert            = errors.New("a client certificate and key must both be provided")
+	ErrInvalidTLSVersion            = errors.New("invalid TLS version. Supported versions are 1.0, 1.1, 1.2, 1.3 and the minimum must not exceed the maximum")
+	ErrInvalidCipherSuites          = errors.New("invalid cipher suites. Expected a comma-separated list of names such as TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256")
+	ErrInvalidCACert                = errors.New("no valid certificates found in the CA file")
+	ErrInvalidRetryPolicy           = errors.New("max attempts and retry backoff must not be negative")
+	ErrInvalidStatusCodes           = errors.New("invalid status codes. Expected a comma-separated list of HTTP status codes")
+	ErrInvalidRetryErrors           = errors.New("invalid retry error classes. Supported classes are " + strings.Join(report.ErrorClasses, ", "))
 )
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Resolve pins a host and port to fixed addresses, like curl's --resolve
+type Resolve struct {
+	Host  string
+	Port  string
+	Addrs []netip.Addr
+}
+
+// ConnectTo sends connections for Host:Port to ToHost:ToPort, like curl's
+// --connect-to. Empty Host or Port match anything; empty ToHost or ToPort
+// keep the original.
+type ConnectTo struct {
+	Host   string
+	Port   string
+	ToHost string
+	ToPort string
+}
+
+// ParseResolve parses host:port:addr[,addr...] entries. IPv6 addresses may
+// be bracketed.
+func ParseResolve(entries []string) ([]Resolve, error) {
+	var resolves []Resolve
+	for _, entry := range entries {
+		host, rest, ok := strings.Cut(entry, ":")
+		if !ok || host == "" {
+			return nil, ErrInvalidResolve
+		}
+		port, rawAddrs, ok := strings.Cut(rest, ":")
+		if !ok || !validPort(port) {
+			return nil, ErrInvalidResolve
+		}
+
+		r := Resolve{Host: strings.ToLower(host), Port: port}
+		for _, raw := range strings.Split(rawAddrs, ",") {
+			addr, err := netip.ParseAddr(strings.Trim(strings.TrimSpace(raw), "[]"))
+			if err != nil {
+				return nil, ErrInvalidResolve
+			}
+			r.Addrs = append(r.Addrs, addr)
+		}
+		resolves = append(resolves, r)
+	}
+	return resolves, nil
+}
+
+// ParseConnectTo parses HOST1:PORT1:HOST2:PORT2 entries. HOST2 may be a
+// bracketed IPv6 address.
+func ParseConnectTo(entries []string) ([]ConnectTo, error) {
+	var mappings []ConnectTo
+	for _, entry := range entries {
+		host, rest, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, ErrInvalidConnectTo
+		}
+		port, to, ok := strings.Cut(rest, ":")
+		i := strings.LastIndex(to, ":")
+		if !ok || i < 0 {
+			return nil, ErrInvalidConnectTo
+		}
+		toHost, toPort := to[:i], to[i+1:]
+
+		if strings.HasPrefix(toHost, "[") {
+			if !strings.HasSuffix(toHost, "]") {
+				return nil, ErrInvalidConnectTo
+			}
+			toHost = toHost[1 : len(toHost)-1]
+		}
+		if (port != "" && !validPort(port)) || (toPort != "" && !validPort(toPort)) {
+			return nil, ErrInvalidConnectTo
+		}
+
+		mappings = append(mappings, ConnectTo{Host: strings.ToLower(host), Port: port, ToHost: toHost, ToPort: toPort})
+	}
+	return mappings, nil
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseResolve(t *testing.T) {
+	resolves, err := ParseResolve([]string{"Example.com:443:10.0.0.1,[::1]", "api.test:80:127.0.0.1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []Resolve{
+		{Host: "example.com", Port: "443", Addrs: []netip.Addr{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("::1")}},
+		{Host: "api.test", Port: "80", Addrs: []netip.Addr{netip.MustParseAddr("127.0.0.1")}},
+	}, resolves)
+
+	for _, entry := range []string{"example.com:443", "example.com:https:10.0.0.1", ":443:10.0.0.1", "example.com:443:backend.local"} {
+		_, err := ParseResolve([]string{entry})
+		assert.ErrorIs(t, err, ErrInvalidResolve, entry)
+	}
+}
+
+func TestParseConnectTo(t *testing.T) {
+	mappings, err := ParseConnectTo([]string{"example.com:443:canary.internal:8443", "::[::1]:", "API.test::10.0.0.2:"})
+	assert.NoError(t, err)
+	assert.Equal(t, []ConnectTo{
+		{Host: "example.com", Port: "443", ToHost: "canary.internal", ToPort: "8443"},
+		{ToHost: "::1"},
+		{Host: "api.test", ToHost: "10.0.0.2"},
+	}, mappings)
+
+	for _, entry := range []string{"example.com:443", "example.com:443:canary", "example.com:https:canary:443", "example.com:443:[::1:443"} {
+		_, err := ParseConnectTo([]string{entry})
+		assert.ErrorIs(t, err, ErrInvalidConnectTo, entry)
+	}
+}
+
+func TestValidateHostOverrides(t *testing.T) {
+	base := Config{URL: "https://example.com/test", Method: "GET", Requests: 1, Timeout: 1, RPS: 1}
+
+	for _, tc := range []struct {
+		mutate   func(*Config)
+		expected error
+	}{
+		{func(c *Config) { c.Resolve = []string{"example.com:443:10.0.0.1"} }, nil},
+		{func(c *Config) { c.Resolve = []string{"example.com:443"} }, ErrInvalidResolve},
+		{func(c *Config) { c.ConnectTo = []string{"example.com:443:canary:"} }, nil},
+		{func(c *Config) { c.ConnectTo = []string{"example.com"} }, ErrInvalidConnectTo},
+		{func(c *Config) { c.ConnectTo = []string{"::canary:"}; c.Proxy = "socks5://proxy:1080" }, nil},
+		{func(c *Config) { c.Resolve = []string{"example.com:443:10.0.0.1"}; c.Proxy = "socks5h://proxy:1080" }, nil},
+		{func(c *Config) { c.ConnectTo = []string{"::canary:"}; c.Proxy = "http://proxy:3128" }, ErrHostOverridesProxy},
+	} {
+		cfg := base
+		tc.mutate(&cfg)
+		if err := cfg.Validate(); err != tc.expected {
+			t.Errorf("expected %v for %v %v, got %v", tc.expected, cfg.Resolve, cfg.ConnectTo, err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// maxSourceAddrs caps how many addresses a CIDR may expand to
+const maxSourceAddrs = 65536
+
+// Source address rotation modes
+const (
+	SourceAddrRoundRobin = "round-robin"
+	SourceAddrRandom     = "random"
+)
+
+// ParseSourceAddrs parses a comma-separated list of local IP addresses and
+// CIDR ranges, e.g. 10.0.0.5,10.0.1.0/29. The network and broadcast
+// addresses of IPv4 ranges larger than /31 are skipped.
+func ParseSourceAddrs(raw string) ([]netip.Addr, error) {
+	var addrs []netip.Addr
+	if strings.TrimSpace(raw) == "" {
+		return addrs, nil
+	}
+
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if !strings.Contains(part, "/") {
+			addr, err := netip.ParseAddr(part)
+			if err != nil {
+				return nil, ErrInvalidSourceAddrs
+			}
+			addrs = append(addrs, addr)
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(part)
+		if err != nil {
+			return nil, ErrInvalidSourceAddrs
+		}
+		prefix = prefix.Masked()
+
+		var r []netip.Addr
+		for a := prefix.Addr(); a.IsValid() && prefix.Contains(a); a = a.Next() {
+			if len(addrs)+len(r) >= maxSourceAddrs {
+				return nil, ErrInvalidSourceAddrs
+			}
+			r = append(r, a)
+		}
+
+		if prefix.Addr().Is4() && prefix.Bits() < 31 {
+			r = r[1 : len(r)-1]
+		}
+		addrs = append(addrs, r...)
+	}
+
+	return addrs, nil
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"crypto/tls"
+	"strings"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion parses a TLS version such as 1.2. An empty string returns 0,
+// leaving the choice to crypto/tls.
+func ParseTLSVersion(raw string) (uint16, error) {
+	raw = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(raw)), "tls")
+	if raw == "" {
+		return 0, nil
+	}
+
+	v, ok := tlsVersions[strings.TrimPrefix(raw, "v")]
+	if !ok {
+		return 0, ErrInvalidTLSVersion
+	}
+	return v, nil
+}
+
+// ParseCipherSuites parses a comma-separated list of cipher suite names as
+// reported by crypto/tls, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+func ParseCipherSuites(raw string) ([]uint16, error) {
+	var ids []uint16
+	if strings.TrimSpace(raw) == "" {
+		return ids, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, s := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		known[s.Name] = s.ID
+	}
+
+	for _, name := range strings.Split(raw, ",") {
+		id, ok := known[strings.TrimSpace(name)]
+		if !ok {
+			return nil, ErrInvalidCipherSuites
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
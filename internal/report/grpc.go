@@ -0,0 +1,83 @@
+package report
+
+import (
+	"fmt"
+	"time"
+)
+
+// GRPCStatusOK is the status of a successful gRPC call
+const GRPCStatusOK = "OK"
+
+// grpcStatuses names the gRPC status codes, indexed by code
+var grpcStatuses = []string{
+	GRPCStatusOK,
+	"CANCELLED",
+	"UNKNOWN",
+	"INVALID_ARGUMENT",
+	"DEADLINE_EXCEEDED",
+	"NOT_FOUND",
+	"ALREADY_EXISTS",
+	"PERMISSION_DENIED",
+	"RESOURCE_EXHAUSTED",
+	"FAILED_PRECONDITION",
+	"ABORTED",
+	"OUT_OF_RANGE",
+	"UNIMPLEMENTED",
+	"INTERNAL",
+	"UNAVAILABLE",
+	"DATA_LOSS",
+	"UNAUTHENTICATED",
+}
+
+// GRPCStatus returns the name of a gRPC status code, e.g. "NOT_FOUND"
+func GRPCStatus(code uint32) string {
+	if int(code) < len(grpcStatuses) {
+		return grpcStatuses[code]
+	}
+	return fmt.Sprintf("CODE_%d", code)
+}
+
+// grpcStatusCode returns the code of a gRPC status name, or -1 for names
+// that are not gRPC statuses
+func grpcStatusCode(name string) int {
+	for code, status := range grpcStatuses {
+		if status == name {
+			return code
+		}
+	}
+	return -1
+}
+
+// GRPC summarises the responses of a gRPC test. The report's status codes
+// are keyed by gRPC status instead of HTTP status code.
+type GRPC struct {
+	Streaming        bool    `json:"streaming"`
+	MessagesReceived int     `json:"messages_received"`
+	MessagesPerCall  float64 `json:"messages_per_call"`
+	// FirstMessage is the time from starting a server-streaming call to
+	// receiving its first message, for the calls that received one
+	FirstMessage *Latency `json:"first_message_latency,omitempty"`
+}
+
+// CalculateGRPC summarises the messages received by the gRPC calls in the
+// results
+func (r *Report) CalculateGRPC(streaming bool) {
+	g := &GRPC{Streaming: streaming}
+	var firstMessages []time.Duration
+
+	for _, result := range r.Results {
+		g.MessagesReceived += result.Messages
+		if result.Messages > 0 && result.FirstMessageTime > 0 {
+			firstMessages = append(firstMessages, result.FirstMessageTime)
+		}
+	}
+
+	if len(r.Results) > 0 {
+		g.MessagesPerCall = float64(g.MessagesReceived) / float64(len(r.Results))
+	}
+	if streaming {
+		first := calculateLatency(firstMessages)
+		g.FirstMessage = &first
+	}
+	r.GRPC = g
+}
@@ -0,0 +1,58 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGRPCStatus(t *testing.T) {
+	for code, want := range map[uint32]string{0: "OK", 5: "NOT_FOUND", 16: "UNAUTHENTICATED", 42: "CODE_42"} {
+		if got := GRPCStatus(code); got != want {
+			t.Errorf("GRPCStatus(%d) = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestCalculateGRPC(t *testing.T) {
+	r := Report{Results: []Result{
+		{GRPCStatus: "UNAVAILABLE", ElapsedTime: time.Millisecond},
+		{GRPCStatus: "OK", ElapsedTime: 30 * time.Millisecond, Messages: 3, FirstMessageTime: 10 * time.Millisecond},
+		{GRPCStatus: "NOT_FOUND", ElapsedTime: 2 * time.Millisecond},
+		{GRPCStatus: "OK", ElapsedTime: 20 * time.Millisecond, Messages: 1, FirstMessageTime: 20 * time.Millisecond},
+	}}
+	r.CalculateStatusCodes()
+	r.CalculateGRPC(true)
+
+	if !r.Results[1].Succeeded() || r.Results[2].Succeeded() {
+		t.Error("expected only OK calls to succeed")
+	}
+	if got := r.StatusCodes.SortedKeys(); strings.Join(got, ",") != "OK,NOT_FOUND,UNAVAILABLE" {
+		t.Errorf("expected statuses in code order, got %v", got)
+	}
+	if r.StatusCodes["OK"].Count != 2 {
+		t.Errorf("expected 2 OK calls, got %+v", r.StatusCodes["OK"])
+	}
+
+	g := r.GRPC
+	if g.MessagesReceived != 4 || g.MessagesPerCall != 1 {
+		t.Errorf("unexpected message summary: %+v", g)
+	}
+	if g.FirstMessage == nil || g.FirstMessage.Min != "10ms" || g.FirstMessage.Max != "20ms" {
+		t.Errorf("unexpected first message latency: %+v", g.FirstMessage)
+	}
+
+	r.TotalRequests = len(r.Results)
+	raw, err := ParseRaw(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"5 NOT_FOUND:", "14 UNAVAILABLE:", "gRPC:", "Messages Received:       4 (1.00 per call)"} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("expected raw report to contain %q", want)
+		}
+	}
+	if strings.Contains(raw, "Server Errors") {
+		t.Error("expected no HTTP error breakdown for a gRPC test")
+	}
+}
@@ -3,7 +3,11 @@ package report
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -42,26 +46,162 @@ func ParseRaw(report Report) (string, error) {
 	builder.WriteString(fmt.Sprintf("  Bytes Sent/Sec:       %.02f\n", report.Throughput.BytesSentPerSecond))
 	builder.WriteString(fmt.Sprintf("  Bytes Received/Sec:   %.02f\n\n", report.Throughput.BytesReceivedPerSecond))
 
-	builder.WriteString("Status Code Breakdown:\n")
-	builder.WriteString(fmt.Sprintf("  200 OK:                 %d\n", report.StatusCodes.Num200))
-	builder.WriteString(fmt.Sprintf("  400 Bad Request:        %d\n", report.StatusCodes.Num400))
-	builder.WriteString(fmt.Sprintf("  403 Forbidden:          %d\n", report.StatusCodes.Num403))
-	builder.WriteString(fmt.Sprintf("  404 Not Found:          %d\n", report.StatusCodes.Num404))
-	builder.WriteString(fmt.Sprintf("  408 Request Timed Out:  %d\n", report.StatusCodes.Num408))
-	builder.WriteString(fmt.Sprintf("  429 Too Many Requests:  %d\n", report.StatusCodes.Num429))
-	builder.WriteString(fmt.Sprintf("  500 Internal Server Error: %d\n", report.StatusCodes.Num500))
-	builder.WriteString(fmt.Sprintf("  502 Bad Gateway:        %d\n", report.StatusCodes.Num502))
-	builder.WriteString(fmt.Sprintf("  503 Service Unavailable: %d\n", report.StatusCodes.Num503))
-	builder.WriteString(fmt.Sprintf("  504 Gateway Timeout:    %d\n\n", report.StatusCodes.Num504))
-
-	builder.WriteString("Error Breakdown:\n")
-	builder.WriteString(fmt.Sprintf("  Server Errors:          %d\n", report.ErrorBreakdown.ServerErrors))
-	builder.WriteString(fmt.Sprintf("  Client Errors:          %d\n", report.ErrorBreakdown.ClientErrors))
-	builder.WriteString("\n")
+	// WebSocket messages have no status codes
+	if ws := report.WebSocket; ws != nil {
+		writeWebSocket(&builder, ws)
+	} else {
+		builder.WriteString("Status Code Breakdown:\n")
+		for _, key := range report.StatusCodes.SortedKeys() {
+			sc := report.StatusCodes[key]
+			builder.WriteString(fmt.Sprintf("  %-28s %d (%.2f%%)\n", statusCodeLabel(key)+":", sc.Count, sc.Percentage))
+			builder.WriteString(fmt.Sprintf("    P50: %s  P95: %s  P99: %s\n", sc.Latency.P50, sc.Latency.P95, sc.Latency.P99))
+		}
+		builder.WriteString("\n")
+
+		// gRPC statuses are not split into client and server errors
+		if g := report.GRPC; g != nil {
+			writeGRPC(&builder, g)
+		} else {
+			builder.WriteString("Error Breakdown:\n")
+			builder.WriteString(fmt.Sprintf("  Server Errors:          %d\n", report.ErrorBreakdown.ServerErrors))
+			builder.WriteString(fmt.Sprintf("  Client Errors:          %d\n", report.ErrorBreakdown.ClientErrors))
+			builder.WriteString("\n")
+		}
+	}
+
+	if len(report.ErrorBreakdown.TransportErrors) > 0 {
+		builder.WriteString("Transport Errors:\n")
+		classes := make([]string, 0, len(report.ErrorBreakdown.TransportErrors))
+		for class := range report.ErrorBreakdown.TransportErrors {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+
+		for _, class := range classes {
+			te := report.ErrorBreakdown.TransportErrors[class]
+			builder.WriteString(fmt.Sprintf("  %-24s %d\n", class+":", te.Count))
+			builder.WriteString(fmt.Sprintf("    First Seen: %s\n", te.FirstSeen.Format(time.RFC3339Nano)))
+			builder.WriteString(fmt.Sprintf("    Last Seen:  %s\n", te.LastSeen.Format(time.RFC3339Nano)))
+			builder.WriteString(fmt.Sprintf("    Sample:     %s\n", te.Sample))
+		}
+		builder.WriteString("\n")
+	}
+
+	if rt := report.Retries; rt != nil {
+		builder.WriteString("Retries:\n")
+		builder.WriteString(fmt.Sprintf("  Total Retries:          %d (%.2f%% added load)\n", rt.TotalRetries, rt.AddedLoadPercent))
+		builder.WriteString(fmt.Sprintf("  Retried Requests:       %d\n", rt.RetriedRequests))
+		builder.WriteString(fmt.Sprintf("  Recovered by Retry:     %d\n", rt.RecoveredRequests))
+		builder.WriteString(fmt.Sprintf("  First Attempt Successes: %d\n", rt.FirstAttemptSuccesses))
+		builder.WriteString(fmt.Sprintf("  First Attempt Failures:  %d\n", rt.FirstAttemptFailures))
+		builder.WriteString(fmt.Sprintf("  Final Successes:         %d\n", report.Successes))
+		builder.WriteString(fmt.Sprintf("  Final Failures:          %d\n", report.Failures))
+		if len(rt.Reasons) > 0 {
+			builder.WriteString("  Retry Reasons:\n")
+			reasons := make([]string, 0, len(rt.Reasons))
+			for reason := range rt.Reasons {
+				reasons = append(reasons, reason)
+			}
+			sort.Strings(reasons)
+			for _, reason := range reasons {
+				builder.WriteString(fmt.Sprintf("    %-22s %d\n", reason+":", rt.Reasons[reason]))
+			}
+		}
+		builder.WriteString("\n")
+	}
+
+	if p := report.Protocols; p != nil {
+		builder.WriteString("Protocols:\n")
+		names := make([]string, 0, len(p.Counts))
+		for name := range p.Counts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			builder.WriteString(fmt.Sprintf("  %-24s %d\n", name+":", p.Counts[name]))
+		}
+		if p.Handshakes > 0 {
+			builder.WriteString(fmt.Sprintf("  Handshakes:              %d\n", p.Handshakes))
+			builder.WriteString(fmt.Sprintf("  Handshake Latency:       min %s, avg %s, p95 %s, max %s\n",
+				p.Handshake.Min, p.Handshake.Avg, p.Handshake.P95, p.Handshake.Max))
+		}
+		builder.WriteString("\n")
+	}
+
+	if c := report.Connections; c != nil {
+		builder.WriteString("Connections:\n")
+		builder.WriteString(fmt.Sprintf("  Opened:                  %d\n", c.Opened))
+		builder.WriteString(fmt.Sprintf("  Fresh Requests:          %d\n", c.FreshRequests))
+		builder.WriteString(fmt.Sprintf("  Reused Requests:         %d\n", c.ReusedRequests))
+		builder.WriteString(fmt.Sprintf("  Requests per Connection: %.2f\n", c.RequestsPerConnection))
+		builder.WriteString("\n")
+	}
+
+	if p := report.ProxyTunnels; p != nil {
+		builder.WriteString("Proxy Tunnels:\n")
+		builder.WriteString(fmt.Sprintf("  Established:             %d\n", p.Count))
+		if p.Count > 0 {
+			builder.WriteString(fmt.Sprintf("  Tunnel Latency:          min %s, avg %s, p95 %s, max %s\n",
+				p.Latency.Min, p.Latency.Avg, p.Latency.P95, p.Latency.Max))
+		}
+		builder.WriteString("\n")
+	}
+
+	if a := report.Authentication; a != nil {
+		builder.WriteString("Authentication (excluded from latency):\n")
+		builder.WriteString(fmt.Sprintf("  Delayed Requests:        %d\n", a.DelayedRequests))
+		builder.WriteString(fmt.Sprintf("  Challenge Resends:       %d\n", a.Resends))
+		if a.DelayedRequests > 0 {
+			builder.WriteString(fmt.Sprintf("  Auth Latency:            min %s, avg %s, p95 %s, max %s\n",
+				a.Latency.Min, a.Latency.Avg, a.Latency.P95, a.Latency.Max))
+		}
+		builder.WriteString("\n")
+	}
+
+	writeAddrStats(&builder, "Source Addresses", report.Sources)
+	writeAddrStats(&builder, "Backend Addresses", report.Backends)
+
+	if v := report.ServerVerification; v != nil {
+		builder.WriteString("Server Verification:\n")
+		builder.WriteString(fmt.Sprintf("  Requests Sent:          %d\n", v.ClientRequests))
+		builder.WriteString(fmt.Sprintf("  Requests Received:      %d\n", v.ServerRequests))
+		builder.WriteString(fmt.Sprintf("  Connections Accepted:   %d\n", v.Server.Connections))
+		builder.WriteString(fmt.Sprintf("  Bytes Received:         %d\n", v.Server.BytesReceived))
+		if len(v.Discrepancies) == 0 {
+			builder.WriteString("  Discrepancies:          none\n")
+		} else {
+			builder.WriteString("  Discrepancies:\n")
+			for _, d := range v.Discrepancies {
+				builder.WriteString(fmt.Sprintf("    - %s\n", d))
+			}
+		}
+		builder.WriteString("\n")
+	}
 
 	return builder.String(), nil
 }
 
+// statusCodeLabel returns a human readable label for a StatusCodes key
+func statusCodeLabel(key string) string {
+	if key == NoResponseKey {
+		return "No Response"
+	}
+
+	if code := grpcStatusCode(key); code >= 0 {
+		return fmt.Sprintf("%d %s", code, key)
+	}
+
+	code, err := strconv.Atoi(key)
+	if err != nil {
+		return key
+	}
+
+	if text := http.StatusText(code); text != "" {
+		return fmt.Sprintf("%d %s", code, text)
+	}
+	return key
+}
+
 func ParseJSON(report Report) (string, error) {
 	jsonStr, err := json.MarshalIndent(report, "", "  ")
 	if err != nil {
@@ -79,3 +219,76 @@ func ParseYAML(report Report) (string, error) {
 
 	return string(yamlStr), nil
 }
+
+// writeAddrStats writes a per IP address breakdown, sorted by address
+func writeAddrStats(builder *strings.Builder, title string, stats map[string]AddrStats) {
+	if len(stats) == 0 {
+		return
+	}
+
+	builder.WriteString(title + ":\n")
+	ips := make([]string, 0, len(stats))
+	for ip := range stats {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	for _, ip := range ips {
+		s := stats[ip]
+		builder.WriteString(fmt.Sprintf("  %s: %d requests (%.2f%%), %d failures, avg %s, p95 %s\n",
+			ip, s.Requests, s.Percentage, s.Failures, s.Latency.Avg, s.Latency.P95))
+		if len(s.Errors) > 0 {
+			keys := make([]string, 0, len(s.Errors))
+			for k := range s.Errors {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				builder.WriteString(fmt.Sprintf("    %s: %d\n", k, s.Errors[k]))
+			}
+		}
+	}
+	builder.WriteString("\n")
+}
+
+// writeWebSocket writes the connection and message summary of a WebSocket test
+func writeWebSocket(builder *strings.Builder, ws *WebSocket) {
+	builder.WriteString("WebSocket:\n")
+	builder.WriteString(fmt.Sprintf("  Connections:             %d (%d established)\n", ws.Connections, ws.Established))
+	if ws.Established > 0 {
+		builder.WriteString(fmt.Sprintf("  Connect Latency:         min %s, avg %s, p95 %s, max %s\n",
+			ws.Connect.Min, ws.Connect.Avg, ws.Connect.P95, ws.Connect.Max))
+	}
+	builder.WriteString(fmt.Sprintf("  Messages Sent:           %d (%.2f/sec)\n", ws.MessagesSent, ws.SentPerSecond))
+	builder.WriteString(fmt.Sprintf("  Messages Received:       %d (%.2f/sec)\n", ws.MessagesReceived, ws.ReceivedPerSecond))
+	writeCounts(builder, "Connect Errors", ws.ConnectErrors)
+	writeCounts(builder, "Disconnect Reasons", ws.Disconnects)
+	builder.WriteString("\n")
+}
+
+// writeGRPC writes the message summary of a gRPC test
+func writeGRPC(builder *strings.Builder, g *GRPC) {
+	builder.WriteString("gRPC:\n")
+	builder.WriteString(fmt.Sprintf("  Streaming:               %t\n", g.Streaming))
+	builder.WriteString(fmt.Sprintf("  Messages Received:       %d (%.2f per call)\n", g.MessagesReceived, g.MessagesPerCall))
+	if f := g.FirstMessage; f != nil {
+		builder.WriteString(fmt.Sprintf("  First Message Latency:   min %s, avg %s, p95 %s, max %s\n", f.Min, f.Avg, f.P95, f.Max))
+	}
+	builder.WriteString("\n")
+}
+
+// writeCounts writes an indented list of counts, sorted by key
+func writeCounts(builder *strings.Builder, title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+
+	builder.WriteString("  " + title + ":\n")
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		builder.WriteString(fmt.Sprintf("    %-22s %d\n", k+":", counts[k]))
+	}
+}
@@ -28,14 +28,11 @@ func sampleReport() Report {
 			TotalBytesReceived: 5000000,
 		},
 		StatusCodes: StatusCodes{
-			Num200: 90,
-			Num400: 5,
-			Num403: 2,
-			Num404: 2,
-			Num500: 1,
-			Num502: 0,
-			Num503: 0,
-			Num504: 0,
+			"200":         {Count: 90, Percentage: 90},
+			"302":         {Count: 3, Percentage: 3},
+			"404":         {Count: 2, Percentage: 2},
+			"500":         {Count: 1, Percentage: 1},
+			NoResponseKey: {Count: 4, Percentage: 4},
 		},
 		ErrorBreakdown: ErrorBreakdown{
 			ServerErrors: 5,
@@ -75,6 +72,12 @@ func TestParseRaw(t *testing.T) {
 	if !strings.Contains(output, "Status Code Breakdown:") {
 		t.Errorf("expected 'Status Code Breakdown' section in raw output, got: %s", output)
 	}
+	if !strings.Contains(output, "302 Found:") {
+		t.Errorf("expected '302 Found' in raw output, got: %s", output)
+	}
+	if !strings.Contains(output, "No Response:") {
+		t.Errorf("expected 'No Response' in raw output, got: %s", output)
+	}
 }
 
 func TestParseJSON(t *testing.T) {
@@ -98,8 +101,8 @@ func TestParseJSON(t *testing.T) {
 	if parsedReport.Latency.Min != "20ms" {
 		t.Errorf("expected Latency.Min '20ms', got %s", parsedReport.Latency.Min)
 	}
-	if parsedReport.StatusCodes.Num200 != 90 {
-		t.Errorf("expected StatusCodes.Num200 90, got %d", parsedReport.StatusCodes.Num200)
+	if parsedReport.StatusCodes["200"].Count != 90 {
+		t.Errorf("expected StatusCodes[200] 90, got %d", parsedReport.StatusCodes["200"].Count)
 	}
 }
 
@@ -120,7 +123,7 @@ func TestParseYAML(t *testing.T) {
 	if parsedReport.Latency.Min != "20ms" {
 		t.Errorf("expected Latency.Min '20ms', got %s", parsedReport.Latency.Min)
 	}
-	if parsedReport.StatusCodes.Num200 != 90 {
-		t.Errorf("expected StatusCodes.Num200 90, got %d", parsedReport.StatusCodes.Num200)
+	if parsedReport.StatusCodes["200"].Count != 90 {
+		t.Errorf("expected StatusCodes[200] 90, got %d", parsedReport.StatusCodes["200"].Count)
 	}
 }
@@ -3,6 +3,7 @@ package report
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"time"
 )
 
@@ -20,6 +21,74 @@ type Report struct {
 	StartTime      string         `json:"start_time"`
 	EndTime        string         `json:"end_time"`
 	Duration       time.Duration  `json:"duration"`
+
+	ServerVerification *ServerVerification  `json:"server_verification,omitempty"`
+	Retries            *Retries             `json:"retries,omitempty"`
+	Protocols          *Protocols           `json:"protocols,omitempty"`
+	Connections        *Connections         `json:"connections,omitempty"`
+	Sources            map[string]AddrStats `json:"sources,omitempty"`
+	Backends           map[string]AddrStats `json:"backends,omitempty"`
+	WebSocket          *WebSocket           `json:"websocket,omitempty"`
+	GRPC               *GRPC                `json:"grpc,omitempty"`
+	ProxyTunnels       *ProxyTunnels        `json:"proxy_tunnels,omitempty"`
+	Authentication     *Authentication      `json:"authentication,omitempty"`
+}
+
+// Authentication summarises the time requests spent waiting for tokens and
+// answering challenges, which is excluded from request latency. Resends
+// counts the requests sent again after a challenge.
+type Authentication struct {
+	DelayedRequests int     `json:"delayed_requests"`
+	Resends         int     `json:"resends"`
+	Latency         Latency `json:"latency"`
+}
+
+// ProxyTunnels summarises the time spent establishing proxy tunnels, which
+// is also included in request latency
+type ProxyTunnels struct {
+	Count   int     `json:"count"`
+	Latency Latency `json:"latency"`
+}
+
+// AddrStats holds the statistics of requests sent from a local IP address or
+// to a backend IP address. Errors counts failures by error class, or by
+// status code for error responses.
+type AddrStats struct {
+	Requests   int            `json:"requests"`
+	Successes  int            `json:"successes"`
+	Failures   int            `json:"failures"`
+	Percentage float64        `json:"percentage"`
+	Latency    Latency        `json:"latency"`
+	Errors     map[string]int `json:"errors,omitempty"`
+}
+
+// Connections describes how requests were spread over connections
+type Connections struct {
+	Opened                int     `json:"opened"`
+	FreshRequests         int     `json:"fresh_requests"`
+	ReusedRequests        int     `json:"reused_requests"`
+	RequestsPerConnection float64 `json:"requests_per_connection"`
+}
+
+// Protocols breaks results down by negotiated protocol and reports the cost
+// of the handshakes that were performed
+type Protocols struct {
+	Counts     map[string]int `json:"counts"`
+	Handshakes int            `json:"handshakes"`
+	Handshake  Latency        `json:"handshake_latency"`
+}
+
+// Retries separates the outcome of first attempts from final outcomes and
+// shows how much load the retries added
+type Retries struct {
+	TotalRetries            int            `json:"total_retries"`
+	RetriedRequests         int            `json:"retried_requests"`
+	RecoveredRequests       int            `json:"recovered_requests"`
+	AddedLoadPercent        float64        `json:"added_load_percent"`
+	FirstAttemptSuccesses   int            `json:"first_attempt_successes"`
+	FirstAttemptFailures    int            `json:"first_attempt_failures"`
+	FirstAttemptStatusCodes map[string]int `json:"first_attempt_status_codes"`
+	Reasons                 map[string]int `json:"reasons"`
 }
 
 type Result struct {
@@ -34,11 +103,150 @@ type Result struct {
 	Timeout       bool          `json:"timeout"`
 	BytesSent     int           `json:"bytes_sent"`
 	BytesReceived int           `json:"bytes_received"`
+	ErrorClass    string        `json:"error_class,omitempty"`
+	Agent         string        `json:"agent,omitempty"`
+
+	// Protocol is the protocol the response arrived over, e.g. "HTTP/3.0".
+	// HandshakeTime is set when the request opened a new connection and
+	// covers the TLS handshake, or the whole QUIC handshake for HTTP/3.
+	Protocol      string        `json:"protocol,omitempty"`
+	HandshakeTime time.Duration `json:"handshake_time,omitempty"`
+
+	// Connection is ConnectionFresh or ConnectionReused when the transport
+	// reports it. ConnectionsOpened counts the connections dialed while the
+	// request waited for one.
+	Connection        string `json:"connection,omitempty"`
+	ConnectionsOpened int    `json:"connections_opened,omitempty"`
+	// SourceIP is the local address of the connection the request used
+	SourceIP string `json:"source_ip,omitempty"`
+	// RemoteIP is the address of the backend the request was sent to
+	RemoteIP string `json:"remote_ip,omitempty"`
+	// ProxyTime is how long the request spent establishing a CONNECT or
+	// SOCKS5 tunnel, zero when it reused one
+	ProxyTime time.Duration `json:"proxy_time,omitempty"`
+	// AuthTime is how long the request spent fetching credentials or
+	// answering an authentication challenge. ElapsedTime excludes it.
+	AuthTime time.Duration `json:"auth_time,omitempty"`
+	// AuthResends is how many times the request was sent again after an
+	// authentication challenge, over all its attempts
+	AuthResends int `json:"auth_resends,omitempty"`
+
+	// Attempts is the number of times the request was sent. For retried
+	// requests the rest of the result describes the final attempt and the
+	// first attempt's outcome is kept separately.
+	Attempts        int      `json:"attempts,omitempty"`
+	FirstResultCode int      `json:"first_result_code,omitempty"`
+	FirstErrorClass string   `json:"first_error_class,omitempty"`
+	RetryReasons    []string `json:"retry_reasons,omitempty"`
+
+	// GRPCStatus is set instead of ResultCode for gRPC calls. Messages is
+	// the number of response messages the call received and
+	// FirstMessageTime how long the first one took.
+	GRPCStatus       string        `json:"grpc_status,omitempty"`
+	Messages         int           `json:"messages,omitempty"`
+	FirstMessageTime time.Duration `json:"first_message_time,omitempty"`
+}
+
+// Succeeded reports whether the request received a non-error response
+func (r Result) Succeeded() bool {
+	if r.GRPCStatus != "" {
+		return r.ErrorClass == "" && r.GRPCStatus == GRPCStatusOK
+	}
+	return r.ErrorClass == "" && r.ResultCode > 0 && r.ResultCode < 400
+}
+
+// statusKey returns the StatusCodes key for the result: its gRPC status, or
+// its HTTP status code
+func (r Result) statusKey() string {
+	if r.GRPCStatus != "" {
+		return r.GRPCStatus
+	}
+	return statusCodeKey(r.ResultCode)
+}
+
+// firstAttempt returns the outcome of the request's first attempt
+func (r Result) firstAttempt() Result {
+	if r.Attempts <= 1 {
+		return r
+	}
+	return Result{ResultCode: r.FirstResultCode, ErrorClass: r.FirstErrorClass}
 }
 
 type ErrorBreakdown struct {
-	ServerErrors int `json:"server_errors"`
-	ClientErrors int `json:"client_errors"`
+	ServerErrors    int                       `json:"server_errors"`
+	ClientErrors    int                       `json:"client_errors"`
+	TransportErrors map[string]TransportError `json:"transport_errors,omitempty"`
+}
+
+// Classes of connection-level errors returned by the HTTP client.
+// ErrorClassConnClosed marks WebSocket messages that were still waiting for a
+// reply when their connection closed, and ErrorClassAuth requests whose
+// credentials could not be obtained, such as a failed OAuth2 token fetch.
+const (
+	ErrorClassDNS             = "dns_failure"
+	ErrorClassConnRefused     = "connection_refused"
+	ErrorClassConnReset       = "connection_reset"
+	ErrorClassTLSHandshake    = "tls_handshake_failure"
+	ErrorClassEOF             = "eof"
+	ErrorClassProxy           = "proxy_error"
+	ErrorClassContextCanceled = "context_canceled"
+	ErrorClassTimeout         = "timeout"
+	ErrorClassBodyRead        = "body_read_error"
+	ErrorClassRequestCreation = "request_creation_error"
+	ErrorClassConnClosed      = "connection_closed"
+	ErrorClassAuth            = "auth_failure"
+	ErrorClassOther           = "other"
+)
+
+// ErrorClasses lists every error class a result can be assigned
+var ErrorClasses = []string{
+	ErrorClassDNS,
+	ErrorClassConnRefused,
+	ErrorClassConnReset,
+	ErrorClassTLSHandshake,
+	ErrorClassEOF,
+	ErrorClassProxy,
+	ErrorClassContextCanceled,
+	ErrorClassTimeout,
+	ErrorClassBodyRead,
+	ErrorClassRequestCreation,
+	ErrorClassConnClosed,
+	ErrorClassAuth,
+	ErrorClassOther,
+}
+
+// TransportError summarises every occurrence of a single error class
+type TransportError struct {
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Sample    string    `json:"sample"`
+}
+
+// AddTransportError records an occurrence of an error class at the given time.
+// The first error message seen for a class is kept as its sample.
+func (e *ErrorBreakdown) AddTransportError(class string, at time.Time, err error) {
+	if e.TransportErrors == nil {
+		e.TransportErrors = make(map[string]TransportError)
+	}
+
+	te, ok := e.TransportErrors[class]
+	if !ok {
+		te.FirstSeen = at
+		if err != nil {
+			te.Sample = err.Error()
+		}
+	}
+
+	te.Count++
+	if at.Before(te.FirstSeen) {
+		te.FirstSeen = at
+	}
+	if at.After(te.LastSeen) {
+		te.LastSeen = at
+	}
+
+	e.TransportErrors[class] = te
 }
 
 type Latency struct {
@@ -57,19 +265,24 @@ type Throughput struct {
 	BytesReceivedPerSecond float64 `json:"bytes_received_per_second"`
 }
 
-type StatusCodes struct {
-	Num200 int `json:"200"`
-	Num201 int `json:"201"`
-	Num204 int `json:"204"`
-	Num400 int `json:"400"`
-	Num403 int `json:"403"`
-	Num404 int `json:"404"`
-	Num408 int `json:"408"`
-	Num429 int `json:"429"`
-	Num500 int `json:"500"`
-	Num502 int `json:"502"`
-	Num503 int `json:"503"`
-	Num504 int `json:"504"`
+// NoResponseKey is the StatusCodes key used for requests that never
+// received an HTTP response (connection errors, timeouts, etc.)
+const NoResponseKey = "no_response"
+
+// Values of Result.Connection
+const (
+	ConnectionFresh  = "fresh"
+	ConnectionReused = "reused"
+)
+
+// StatusCodes maps every observed status code (as a string, e.g. "200")
+// to its statistics. Requests without a response are keyed by NoResponseKey.
+type StatusCodes map[string]StatusCode
+
+type StatusCode struct {
+	Count      int     `json:"count"`
+	Percentage float64 `json:"percentage"`
+	Latency    Latency `json:"latency"`
 }
 
 func (r *Report) CalculateLatencyMetrics() {
@@ -79,24 +292,38 @@ func (r *Report) CalculateLatencyMetrics() {
 	}
 
 	var latencies []time.Duration
-	var totalLatency time.Duration
 	for _, result := range r.Results {
 		latencies = append(latencies, result.ElapsedTime)
-		totalLatency += result.ElapsedTime
 	}
 
-	sort.Slice(latencies, func(i, j int) bool {
-		return latencies[i] < latencies[j]
+	r.Latency = calculateLatency(latencies)
+}
+
+// calculateLatency computes min/max/avg and percentiles for a set of latencies
+func calculateLatency(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
 	})
 
-	minLatency := latencies[0]
-	maxLatency := latencies[len(latencies)-1]
-	avgLatency := totalLatency / time.Duration(len(latencies))
-	p50 := latencies[len(latencies)*50/100]
-	p95 := latencies[len(latencies)*95/100]
-	p99 := latencies[len(latencies)*99/100]
+	var totalLatency time.Duration
+	for _, l := range sorted {
+		totalLatency += l
+	}
+
+	minLatency := sorted[0]
+	maxLatency := sorted[len(sorted)-1]
+	avgLatency := totalLatency / time.Duration(len(sorted))
+	p50 := sorted[len(sorted)*50/100]
+	p95 := sorted[len(sorted)*95/100]
+	p99 := sorted[len(sorted)*99/100]
 
-	r.Latency = Latency{
+	return Latency{
 		Min: formatDuration(minLatency),
 		Max: formatDuration(maxLatency),
 		Avg: formatDuration(avgLatency),
@@ -111,37 +338,234 @@ func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%v", d)
 }
 
-func (r *Report) ConvertResultCodes(m map[int]int) {
-	statusCodes := StatusCodes{}
+// CalculateStatusCodes builds the status code breakdown from the results,
+// including counts, percentages and latency percentiles for every code seen
+func (r *Report) CalculateStatusCodes() {
+	latencies := make(map[string][]time.Duration)
+	for _, result := range r.Results {
+		key := result.statusKey()
+		latencies[key] = append(latencies[key], result.ElapsedTime)
+	}
 
-	for resultCode, count := range m {
-		switch resultCode {
-		case 200:
-			statusCodes.Num200 = count
-		case 201:
-			statusCodes.Num201 = count
-		case 204:
-			statusCodes.Num204 = count
-		case 400:
-			statusCodes.Num400 = count
-		case 403:
-			statusCodes.Num403 = count
-		case 404:
-			statusCodes.Num404 = count
-		case 408:
-			statusCodes.Num408 = count
-		case 429:
-			statusCodes.Num429 = count
-		case 500:
-			statusCodes.Num500 = count
-		case 502:
-			statusCodes.Num502 = count
-		case 503:
-			statusCodes.Num503 = count
-		case 504:
-			statusCodes.Num504 = count
+	statusCodes := StatusCodes{}
+	for key, l := range latencies {
+		sc := StatusCode{
+			Count:   len(l),
+			Latency: calculateLatency(l),
+		}
+		if len(r.Results) > 0 {
+			sc.Percentage = float64(len(l)) / float64(len(r.Results)) * 100
 		}
+		statusCodes[key] = sc
 	}
 
 	r.StatusCodes = statusCodes
 }
+
+// CalculateRetries summarises retries across all results. Final outcomes are
+// the regular status codes, successes and failures.
+func (r *Report) CalculateRetries() {
+	retries := &Retries{
+		FirstAttemptStatusCodes: make(map[string]int),
+		Reasons:                 make(map[string]int),
+	}
+
+	for _, result := range r.Results {
+		first := result.firstAttempt()
+		retries.FirstAttemptStatusCodes[statusCodeKey(first.ResultCode)]++
+		if first.Succeeded() {
+			retries.FirstAttemptSuccesses++
+		} else {
+			retries.FirstAttemptFailures++
+		}
+
+		if result.Attempts > 1 {
+			retries.RetriedRequests++
+			retries.TotalRetries += result.Attempts - 1
+			if result.Succeeded() {
+				retries.RecoveredRequests++
+			}
+		}
+
+		for _, reason := range result.RetryReasons {
+			retries.Reasons[reason]++
+		}
+	}
+
+	if len(r.Results) > 0 {
+		retries.AddedLoadPercent = float64(retries.TotalRetries) / float64(len(r.Results)) * 100
+	}
+
+	r.Retries = retries
+}
+
+// CalculateProtocols counts results per negotiated protocol and summarises
+// handshake latency. It leaves Protocols nil when no protocol was recorded.
+func (r *Report) CalculateProtocols() {
+	protocols := &Protocols{Counts: make(map[string]int)}
+	var handshakes []time.Duration
+
+	for _, result := range r.Results {
+		if result.Protocol != "" {
+			protocols.Counts[result.Protocol]++
+		}
+		if result.HandshakeTime > 0 {
+			handshakes = append(handshakes, result.HandshakeTime)
+		}
+	}
+
+	if len(protocols.Counts) == 0 && len(handshakes) == 0 {
+		return
+	}
+
+	protocols.Handshakes = len(handshakes)
+	protocols.Handshake = calculateLatency(handshakes)
+	r.Protocols = protocols
+}
+
+// CalculateConnections summarises connection usage. It leaves Connections nil
+// when no result recorded any connection information.
+func (r *Report) CalculateConnections() {
+	connections := &Connections{}
+	var requests int
+
+	for _, result := range r.Results {
+		connections.Opened += result.ConnectionsOpened
+		switch result.Connection {
+		case ConnectionFresh:
+			connections.FreshRequests++
+		case ConnectionReused:
+			connections.ReusedRequests++
+		}
+		if result.Connection != "" || result.ResultCode > 0 {
+			requests++
+		}
+	}
+
+	if connections.Opened == 0 && connections.FreshRequests == 0 && connections.ReusedRequests == 0 {
+		return
+	}
+
+	if connections.Opened > 0 {
+		connections.RequestsPerConnection = float64(requests) / float64(connections.Opened)
+	}
+	r.Connections = connections
+}
+
+// CalculateSources breaks the results down by local source IP. Requests that
+// never got a connection are not attributed to any source.
+func (r *Report) CalculateSources() {
+	r.Sources = r.breakdownByAddr(func(result Result) string { return result.SourceIP })
+}
+
+// CalculateBackends breaks the results down by the backend IP they were sent
+// to. Requests whose connection failed are attributed to the address that
+// was dialed.
+func (r *Report) CalculateBackends() {
+	r.Backends = r.breakdownByAddr(func(result Result) string { return result.RemoteIP })
+}
+
+// breakdownByAddr groups the results by the IP address addr returns,
+// skipping results without one
+func (r *Report) breakdownByAddr(addr func(Result) string) map[string]AddrStats {
+	latencies := make(map[string][]time.Duration)
+	stats := make(map[string]AddrStats)
+
+	for _, result := range r.Results {
+		ip := addr(result)
+		if ip == "" {
+			continue
+		}
+		s := stats[ip]
+		s.Requests++
+		if result.Succeeded() {
+			s.Successes++
+		} else {
+			s.Failures++
+			if s.Errors == nil {
+				s.Errors = make(map[string]int)
+			}
+			if result.ErrorClass != "" {
+				s.Errors[result.ErrorClass]++
+			} else {
+				s.Errors[result.statusKey()]++
+			}
+		}
+		stats[ip] = s
+		latencies[ip] = append(latencies[ip], result.ElapsedTime)
+	}
+
+	for ip, s := range stats {
+		s.Latency = calculateLatency(latencies[ip])
+		if len(r.Results) > 0 {
+			s.Percentage = float64(s.Requests) / float64(len(r.Results)) * 100
+		}
+		stats[ip] = s
+	}
+
+	return stats
+}
+
+// CalculateProxyTunnels summarises the proxy tunnels opened during the test
+func (r *Report) CalculateProxyTunnels() {
+	var tunnels []time.Duration
+	for _, result := range r.Results {
+		if result.ProxyTime > 0 {
+			tunnels = append(tunnels, result.ProxyTime)
+		}
+	}
+
+	r.ProxyTunnels = &ProxyTunnels{Count: len(tunnels), Latency: calculateLatency(tunnels)}
+}
+
+// CalculateAuthentication summarises the time spent on authentication
+func (r *Report) CalculateAuthentication() {
+	var delays []time.Duration
+	resends := 0
+	for _, result := range r.Results {
+		if result.AuthTime > 0 {
+			delays = append(delays, result.AuthTime)
+		}
+		resends += result.AuthResends
+	}
+
+	r.Authentication = &Authentication{DelayedRequests: len(delays), Resends: resends, Latency: calculateLatency(delays)}
+}
+
+// SortedKeys returns the status code keys in ascending numeric order, with
+// NoResponseKey last. gRPC statuses are ordered by their code.
+func (s StatusCodes) SortedKeys() []string {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i] == NoResponseKey {
+			return false
+		}
+		if keys[j] == NoResponseKey {
+			return true
+		}
+		return statusKeyOrder(keys[i]) < statusKeyOrder(keys[j])
+	})
+
+	return keys
+}
+
+// statusKeyOrder returns the code a StatusCodes key sorts by
+func statusKeyOrder(key string) int {
+	if code := grpcStatusCode(key); code >= 0 {
+		return code
+	}
+	code, _ := strconv.Atoi(key)
+	return code
+}
+
+// statusCodeKey returns the StatusCodes key for a result code
+func statusCodeKey(code int) string {
+	if code == 0 {
+		return NoResponseKey
+	}
+	return strconv.Itoa(code)
+}
@@ -1,6 +1,7 @@
 package report
 
 import (
+	"errors"
 	"testing"
 	"time"
 )
@@ -48,56 +49,60 @@ func TestCalculateLatencyMetrics(t *testing.T) {
 	}
 }
 
-// Test for ConvertResultCodes
-func TestConvertResultCodes(t *testing.T) {
-	// Sample status code map
-	statusCodeMap := map[int]int{
-		200: 50,
-		201: 10,
-		204: 5,
-		400: 3,
-		403: 2,
-		404: 7,
-		500: 1,
-		502: 0,
-		503: 4,
-		504: 6,
-	}
-
-	// Create a Report and convert result codes
-	report := Report{}
-	report.ConvertResultCodes(statusCodeMap)
-
-	// Assertions
-	if report.StatusCodes.Num200 != 50 {
-		t.Errorf("expected 50 for 200 OK, got %d", report.StatusCodes.Num200)
-	}
-	if report.StatusCodes.Num201 != 10 {
-		t.Errorf("expected 10 for 201 Created, got %d", report.StatusCodes.Num201)
-	}
-	if report.StatusCodes.Num204 != 5 {
-		t.Errorf("expected 5 for 204 No Content, got %d", report.StatusCodes.Num204)
+// Test for CalculateStatusCodes
+func TestCalculateStatusCodes(t *testing.T) {
+	report := Report{
+		Results: []Result{
+			{ResultCode: 200, ElapsedTime: 100 * time.Millisecond},
+			{ResultCode: 200, ElapsedTime: 300 * time.Millisecond},
+			{ResultCode: 302, ElapsedTime: 50 * time.Millisecond},
+			{ResultCode: 499, ElapsedTime: 10 * time.Millisecond},
+			{ResultCode: 0, ElapsedTime: 2 * time.Second},
+		},
+		TotalRequests: 5,
 	}
-	if report.StatusCodes.Num400 != 3 {
-		t.Errorf("expected 3 for 400 Bad Request, got %d", report.StatusCodes.Num400)
+	report.CalculateStatusCodes()
+
+	expected := map[string]int{
+		"200":         2,
+		"302":         1,
+		"499":         1,
+		NoResponseKey: 1,
 	}
-	if report.StatusCodes.Num403 != 2 {
-		t.Errorf("expected 2 for 403 Forbidden, got %d", report.StatusCodes.Num403)
+
+	if len(report.StatusCodes) != len(expected) {
+		t.Fatalf("expected %d status codes, got %d", len(expected), len(report.StatusCodes))
 	}
-	if report.StatusCodes.Num404 != 7 {
-		t.Errorf("expected 7 for 404 Not Found, got %d", report.StatusCodes.Num404)
+
+	for key, count := range expected {
+		if report.StatusCodes[key].Count != count {
+			t.Errorf("expected %d for %s, got %d", count, key, report.StatusCodes[key].Count)
+		}
 	}
-	if report.StatusCodes.Num500 != 1 {
-		t.Errorf("expected 1 for 500 Internal Server Error, got %d", report.StatusCodes.Num500)
+
+	if report.StatusCodes["200"].Percentage != 40 {
+		t.Errorf("expected 40%% for 200, got %.2f", report.StatusCodes["200"].Percentage)
 	}
-	if report.StatusCodes.Num502 != 0 {
-		t.Errorf("expected 0 for 502 Bad Gateway, got %d", report.StatusCodes.Num502)
+	if report.StatusCodes["200"].Latency.Max != "300ms" {
+		t.Errorf("expected max latency 300ms for 200, got %s", report.StatusCodes["200"].Latency.Max)
 	}
-	if report.StatusCodes.Num503 != 4 {
-		t.Errorf("expected 4 for 503 Service Unavailable, got %d", report.StatusCodes.Num503)
+}
+
+// Test for StatusCodes.SortedKeys
+func TestStatusCodesSortedKeys(t *testing.T) {
+	statusCodes := StatusCodes{
+		NoResponseKey: {Count: 1},
+		"503":         {Count: 1},
+		"200":         {Count: 1},
+		"404":         {Count: 1},
 	}
-	if report.StatusCodes.Num504 != 6 {
-		t.Errorf("expected 6 for 504 Gateway Timeout, got %d", report.StatusCodes.Num504)
+
+	expected := []string{"200", "404", "503", NoResponseKey}
+	keys := statusCodes.SortedKeys()
+	for i := range expected {
+		if keys[i] != expected[i] {
+			t.Errorf("expected key %s at position %d, got %s", expected[i], i, keys[i])
+		}
 	}
 }
 
@@ -119,3 +124,191 @@ func TestFormatDuration(t *testing.T) {
 		}
 	}
 }
+
+// Test for ErrorBreakdown.AddTransportError
+func TestAddTransportError(t *testing.T) {
+	eb := ErrorBreakdown{}
+	first := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	last := first.Add(time.Minute)
+
+	eb.AddTransportError(ErrorClassConnRefused, last, errors.New("dial tcp: connection refused"))
+	eb.AddTransportError(ErrorClassConnRefused, first, errors.New("another refusal"))
+	eb.AddTransportError(ErrorClassDNS, first, errors.New("no such host"))
+
+	refused := eb.TransportErrors[ErrorClassConnRefused]
+	if refused.Count != 2 {
+		t.Errorf("expected 2 connection refused errors, got %d", refused.Count)
+	}
+	if !refused.FirstSeen.Equal(first) || !refused.LastSeen.Equal(last) {
+		t.Errorf("expected first/last seen %s/%s, got %s/%s", first, last, refused.FirstSeen, refused.LastSeen)
+	}
+	if refused.Sample != "dial tcp: connection refused" {
+		t.Errorf("expected sample to be the first message, got %q", refused.Sample)
+	}
+	if eb.TransportErrors[ErrorClassDNS].Count != 1 {
+		t.Errorf("expected 1 DNS error, got %d", eb.TransportErrors[ErrorClassDNS].Count)
+	}
+}
+
+// Test for CalculateRetries
+func TestCalculateRetries(t *testing.T) {
+	report := Report{
+		Results: []Result{
+			{ResultCode: 200, Attempts: 1},
+			{ResultCode: 200, Attempts: 3, FirstResultCode: 503, RetryReasons: []string{"503", "503"}},
+			{ResultCode: 503, Attempts: 2, FirstResultCode: 0, FirstErrorClass: ErrorClassConnReset, RetryReasons: []string{ErrorClassConnReset}},
+			{ResultCode: 404, Attempts: 1},
+		},
+	}
+	report.CalculateRetries()
+
+	r := report.Retries
+	if r.TotalRetries != 3 || r.RetriedRequests != 2 || r.RecoveredRequests != 1 {
+		t.Errorf("expected 3 retries over 2 requests with 1 recovered, got %d, %d, %d", r.TotalRetries, r.RetriedRequests, r.RecoveredRequests)
+	}
+	if r.AddedLoadPercent != 75 {
+		t.Errorf("expected 75%% added load, got %.2f", r.AddedLoadPercent)
+	}
+	if r.FirstAttemptSuccesses != 1 || r.FirstAttemptFailures != 3 {
+		t.Errorf("expected 1 first attempt success and 3 failures, got %d and %d", r.FirstAttemptSuccesses, r.FirstAttemptFailures)
+	}
+	if r.FirstAttemptStatusCodes["503"] != 1 || r.FirstAttemptStatusCodes[NoResponseKey] != 1 {
+		t.Errorf("unexpected first attempt status codes: %v", r.FirstAttemptStatusCodes)
+	}
+	if r.Reasons["503"] != 2 || r.Reasons[ErrorClassConnReset] != 1 {
+		t.Errorf("unexpected retry reasons: %v", r.Reasons)
+	}
+}
+
+func TestCalculateProtocols(t *testing.T) {
+	report := Report{
+		Results: []Result{
+			{ResultCode: 200, Protocol: "HTTP/3.0", HandshakeTime: 10 * time.Millisecond},
+			{ResultCode: 200, Protocol: "HTTP/3.0"},
+			{ResultCode: 200, Protocol: "HTTP/1.1", HandshakeTime: 30 * time.Millisecond},
+			{ErrorClass: ErrorClassConnRefused},
+		},
+	}
+	report.CalculateProtocols()
+
+	p := report.Protocols
+	if p == nil {
+		t.Fatal("expected a protocol breakdown")
+	}
+	if p.Counts["HTTP/3.0"] != 2 || p.Counts["HTTP/1.1"] != 1 {
+		t.Errorf("unexpected protocol counts: %v", p.Counts)
+	}
+	if p.Handshakes != 2 || p.Handshake.Min != "10ms" || p.Handshake.Max != "30ms" {
+		t.Errorf("unexpected handshake summary: %d %+v", p.Handshakes, p.Handshake)
+	}
+
+	empty := Report{Results: []Result{{ErrorClass: ErrorClassConnRefused}}}
+	empty.CalculateProtocols()
+	if empty.Protocols != nil {
+		t.Errorf("expected no protocol breakdown, got %+v", empty.Protocols)
+	}
+}
+
+func TestCalculateConnections(t *testing.T) {
+	report := Report{
+		Results: []Result{
+			{ResultCode: 200, Connection: ConnectionFresh, ConnectionsOpened: 1},
+			{ResultCode: 200, Connection: ConnectionReused},
+			{ResultCode: 200, Connection: ConnectionReused},
+			{ResultCode: 200, Connection: ConnectionFresh, ConnectionsOpened: 1},
+		},
+	}
+	report.CalculateConnections()
+
+	c := report.Connections
+	if c == nil {
+		t.Fatal("expected a connection summary")
+	}
+	if c.Opened != 2 || c.FreshRequests != 2 || c.ReusedRequests != 2 || c.RequestsPerConnection != 2 {
+		t.Errorf("unexpected connection summary: %+v", c)
+	}
+
+	empty := Report{Results: []Result{{ErrorClass: ErrorClassConnRefused}}}
+	empty.CalculateConnections()
+	if empty.Connections != nil {
+		t.Errorf("expected no connection summary, got %+v", empty.Connections)
+	}
+}
+
+func TestCalculateSources(t *testing.T) {
+	report := Report{
+		Results: []Result{
+			{ResultCode: 200, SourceIP: "10.0.0.1", ElapsedTime: 10 * time.Millisecond},
+			{ResultCode: 503, SourceIP: "10.0.0.1", ElapsedTime: 30 * time.Millisecond},
+			{ResultCode: 200, SourceIP: "10.0.0.2", ElapsedTime: 20 * time.Millisecond},
+			{ErrorClass: ErrorClassConnRefused},
+		},
+	}
+	report.CalculateSources()
+
+	if len(report.Sources) != 2 {
+		t.Fatalf("expected 2 sources, got %v", report.Sources)
+	}
+	s := report.Sources["10.0.0.1"]
+	if s.Requests != 2 || s.Successes != 1 || s.Failures != 1 || s.Percentage != 50 || s.Latency.Max != "30ms" {
+		t.Errorf("unexpected stats for 10.0.0.1: %+v", s)
+	}
+}
+
+func TestCalculateProxyTunnels(t *testing.T) {
+	report := Report{
+		Results: []Result{
+			{ResultCode: 200, ProxyTime: 4 * time.Millisecond},
+			{ResultCode: 200},
+			{ResultCode: 200, ProxyTime: 8 * time.Millisecond},
+		},
+	}
+	report.CalculateProxyTunnels()
+
+	p := report.ProxyTunnels
+	if p.Count != 2 || p.Latency.Min != "4ms" || p.Latency.Max != "8ms" {
+		t.Errorf("unexpected proxy tunnel summary: %+v", p)
+	}
+}
+
+func TestCalculateAuthentication(t *testing.T) {
+	report := Report{
+		Results: []Result{
+			{ResultCode: 200, AuthTime: 120 * time.Millisecond, AuthResends: 1},
+			{ResultCode: 200},
+			{ResultCode: 200, AuthResends: 1},
+		},
+	}
+	report.CalculateAuthentication()
+
+	a := report.Authentication
+	if a.DelayedRequests != 1 || a.Resends != 2 || a.Latency.Max != "120ms" {
+		t.Errorf("unexpected authentication summary: %+v", a)
+	}
+}
+
+func TestCalculateBackends(t *testing.T) {
+	report := Report{
+		Results: []Result{
+			{ResultCode: 200, RemoteIP: "10.0.0.1", ElapsedTime: 10 * time.Millisecond},
+			{ResultCode: 503, RemoteIP: "10.0.0.1", ElapsedTime: 30 * time.Millisecond},
+			{ErrorClass: ErrorClassConnRefused, RemoteIP: "10.0.0.2"},
+			{ResultCode: 200, RemoteIP: "10.0.0.3", ElapsedTime: 20 * time.Millisecond},
+		},
+	}
+	report.CalculateBackends()
+
+	if len(report.Backends) != 3 {
+		t.Fatalf("expected 3 backends, got %v", report.Backends)
+	}
+	b := report.Backends["10.0.0.1"]
+	if b.Requests != 2 || b.Failures != 1 || b.Errors["503"] != 1 || b.Latency.Max != "30ms" {
+		t.Errorf("unexpected stats for 10.0.0.1: %+v", b)
+	}
+	if report.Backends["10.0.0.2"].Errors[ErrorClassConnRefused] != 1 {
+		t.Errorf("expected a refused connection for 10.0.0.2, got %+v", report.Backends["10.0.0.2"])
+	}
+	if report.Backends["10.0.0.3"].Errors != nil {
+		t.Errorf("expected no errors for 10.0.0.3, got %v", report.Backends["10.0.0.3"].Errors)
+	}
+}
@@ -0,0 +1,93 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ServerStats is what the test server says it received, as served at /_stats
+type ServerStats struct {
+	Since         time.Time      `json:"since"`
+	Requests      int            `json:"requests"`
+	Methods       map[string]int `json:"methods"`
+	Paths         map[string]int `json:"paths"`
+	Statuses      map[string]int `json:"statuses"`
+	Protocols     map[string]int `json:"protocols"`
+	BytesReceived int64          `json:"bytes_received"`
+	Connections   int            `json:"connections"`
+}
+
+// ServerVerification compares what the client sent with what the server received
+type ServerVerification struct {
+	ClientRequests int         `json:"client_requests"`
+	ServerRequests int         `json:"server_requests"`
+	Server         ServerStats `json:"server"`
+	Discrepancies  []string    `json:"discrepancies"`
+}
+
+// VerifyServer cross-checks the report against the server's own counters and
+// records any discrepancies. Requests that never reached the server (DNS or
+// connection failures) are expected to show up as a shortfall, and requests
+// that got no response are left out of the status comparison.
+func (r *Report) VerifyServer(stats ServerStats) {
+	// every retry and every resend after an authentication challenge is a
+	// request of its own as far as the server is concerned
+	sent := r.TotalRequests
+	if r.Retries != nil {
+		sent += r.Retries.TotalRetries
+	}
+	if r.Authentication != nil {
+		sent += r.Authentication.Resends
+	}
+
+	v := &ServerVerification{
+		ClientRequests: sent,
+		ServerRequests: stats.Requests,
+		Server:         stats,
+		Discrepancies:  []string{},
+	}
+
+	if stats.Requests < sent {
+		v.Discrepancies = append(v.Discrepancies, fmt.Sprintf("server received %d requests but %d were sent (%d missing)", stats.Requests, sent, sent-stats.Requests))
+	} else if stats.Requests > sent {
+		v.Discrepancies = append(v.Discrepancies, fmt.Sprintf("server received %d requests but only %d were sent (%d extra)", stats.Requests, sent, stats.Requests-sent))
+	}
+
+	// unanswered requests have no status on either side. The server may still
+	// have answered the ones the client gave up on, the attempts that were
+	// retried and the challenged requests, with any status.
+	codes := make(map[string]bool)
+	for code := range stats.Statuses {
+		if code != NoResponseKey {
+			codes[code] = true
+		}
+	}
+	for code := range r.StatusCodes {
+		if code != NoResponseKey {
+			codes[code] = true
+		}
+	}
+	unseen := r.ErrorBreakdown.TransportErrors[ErrorClassTimeout].Count + sent - r.TotalRequests
+
+	sorted := make([]string, 0, len(codes))
+	for code := range codes {
+		sorted = append(sorted, code)
+	}
+	sort.Strings(sorted)
+
+	for _, code := range sorted {
+		served := stats.Statuses[code]
+		observed := r.StatusCodes[code].Count
+		diff := served - observed
+		if extra := min(diff, unseen); extra > 0 {
+			diff -= extra
+			unseen -= extra
+		}
+		if diff != 0 {
+			v.Discrepancies = append(v.Discrepancies, fmt.Sprintf("status %s: server sent %d but client observed %d", code, served, observed))
+		}
+	}
+
+	r.ServerVerification = v
+}
@@ -0,0 +1,110 @@
+package report
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyServer(t *testing.T) {
+	report := Report{
+		TotalRequests: 10,
+		StatusCodes: StatusCodes{
+			"200":         {Count: 7},
+			"503":         {Count: 2},
+			NoResponseKey: {Count: 1},
+		},
+	}
+
+	report.VerifyServer(ServerStats{
+		Requests: 9,
+		Statuses: map[string]int{"200": 7, "503": 2},
+	})
+
+	v := report.ServerVerification
+	if v == nil {
+		t.Fatal("expected server verification to be set")
+	}
+	if v.ClientRequests != 10 || v.ServerRequests != 9 {
+		t.Errorf("expected 10 sent and 9 received, got %d and %d", v.ClientRequests, v.ServerRequests)
+	}
+	if len(v.Discrepancies) != 1 || !strings.Contains(v.Discrepancies[0], "1 missing") {
+		t.Errorf("expected a single missing request discrepancy, got %v", v.Discrepancies)
+	}
+
+	report.VerifyServer(ServerStats{
+		Requests: 10,
+		Statuses: map[string]int{"200": 8, "503": 2},
+	})
+	if len(report.ServerVerification.Discrepancies) != 1 || !strings.Contains(report.ServerVerification.Discrepancies[0], "status 200") {
+		t.Errorf("expected a status 200 discrepancy, got %v", report.ServerVerification.Discrepancies)
+	}
+}
+
+func TestVerifyServerUnanswered(t *testing.T) {
+	report := Report{
+		TotalRequests: 10,
+		StatusCodes: StatusCodes{
+			"200":         {Count: 7},
+			NoResponseKey: {Count: 3},
+		},
+		ErrorBreakdown: ErrorBreakdown{
+			TransportErrors: map[string]TransportError{ErrorClassTimeout: {Count: 2}},
+		},
+	}
+
+	// the server answered both timed out requests late and hung up on the last
+	report.VerifyServer(ServerStats{
+		Requests: 10,
+		Statuses: map[string]int{"200": 9, NoResponseKey: 1},
+	})
+	if d := report.ServerVerification.Discrepancies; len(d) != 0 {
+		t.Errorf("expected no discrepancies, got %v", d)
+	}
+
+	report.VerifyServer(ServerStats{
+		Requests: 10,
+		Statuses: map[string]int{"200": 10},
+	})
+	if d := report.ServerVerification.Discrepancies; len(d) != 1 || !strings.Contains(d[0], "status 200") {
+		t.Errorf("expected a status 200 discrepancy, got %v", d)
+	}
+}
+
+func TestVerifyServerRetries(t *testing.T) {
+	report := Report{
+		TotalRequests: 4,
+		StatusCodes:   StatusCodes{"200": {Count: 4}},
+		Retries:       &Retries{TotalRetries: 2},
+	}
+
+	// two requests were answered with 503 before their retry succeeded
+	report.VerifyServer(ServerStats{
+		Requests: 6,
+		Statuses: map[string]int{"200": 4, "503": 2},
+	})
+
+	v := report.ServerVerification
+	if v.ClientRequests != 6 {
+		t.Errorf("expected 6 requests sent including retries, got %d", v.ClientRequests)
+	}
+	if len(v.Discrepancies) != 0 {
+		t.Errorf("expected no discrepancies, got %v", v.Discrepancies)
+	}
+}
+
+func TestVerifyServerAuthResends(t *testing.T) {
+	report := Report{
+		TotalRequests:  3,
+		StatusCodes:    StatusCodes{"200": {Count: 3}},
+		Authentication: &Authentication{Resends: 1},
+	}
+
+	// one request was challenged and sent again with fresh credentials
+	report.VerifyServer(ServerStats{
+		Requests: 4,
+		Statuses: map[string]int{"200": 3, "401": 1},
+	})
+	if d := report.ServerVerification.Discrepancies; len(d) != 0 {
+		t.Errorf("expected no discrepancies, got %v", d)
+	}
+}
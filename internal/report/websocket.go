@@ -0,0 +1,97 @@
+package report
+
+import (
+	"time"
+)
+
+// Reasons a WebSocket connection ended, besides close codes and error classes
+const (
+	DisconnectClientClosed = "closed_by_client"
+)
+
+// WebSocket summarises a WebSocket test. The report's Results hold one entry
+// per message sent, timed from sending it until the reply that echoes it
+// arrives, or, for replies that echo no message, until it is the oldest
+// message waiting on its connection when a reply arrives.
+type WebSocket struct {
+	Connections       int            `json:"connections"`
+	Established       int            `json:"established"`
+	Connect           Latency        `json:"connect_latency"`
+	ConnectErrors     map[string]int `json:"connect_errors,omitempty"`
+	MessagesSent      int            `json:"messages_sent"`
+	MessagesReceived  int            `json:"messages_received"`
+	SentPerSecond     float64        `json:"sent_per_second"`
+	ReceivedPerSecond float64        `json:"received_per_second"`
+	Disconnects       map[string]int `json:"disconnects,omitempty"`
+}
+
+// WebSocketConn is the outcome of a single WebSocket connection
+type WebSocketConn struct {
+	// ConnectTime covers the TCP, TLS and upgrade handshakes
+	ConnectTime time.Duration
+	// ConnectError is set, to a status code or error class, when the
+	// connection could not be established
+	ConnectError string
+	// Disconnect is why an established connection ended
+	Disconnect string
+	Received   int
+}
+
+// NewWebSocketReport builds the report of a WebSocket test from the results
+// of every message and the outcome of every connection
+func NewWebSocketReport(target string, start, end time.Time, results []Result, conns []WebSocketConn) Report {
+	r := Report{
+		Host:          target,
+		Method:        "WS",
+		Results:       results,
+		TotalRequests: len(results),
+		StartTime:     start.Format(time.RFC3339Nano),
+		EndTime:       end.Format(time.RFC3339Nano),
+		Duration:      end.Sub(start),
+	}
+
+	ws := &WebSocket{Connections: len(conns), MessagesSent: len(results)}
+	var connectTimes, roundTrips []time.Duration
+	for _, c := range conns {
+		if c.ConnectError != "" {
+			if ws.ConnectErrors == nil {
+				ws.ConnectErrors = make(map[string]int)
+			}
+			ws.ConnectErrors[c.ConnectError]++
+			continue
+		}
+
+		ws.Established++
+		ws.MessagesReceived += c.Received
+		connectTimes = append(connectTimes, c.ConnectTime)
+		if ws.Disconnects == nil {
+			ws.Disconnects = make(map[string]int)
+		}
+		ws.Disconnects[c.Disconnect]++
+	}
+
+	for _, result := range results {
+		r.Throughput.TotalBytesSent += result.BytesSent
+		r.Throughput.TotalBytesReceived += result.BytesReceived
+		if result.ErrorClass != "" {
+			r.Failures++
+			r.ErrorBreakdown.AddTransportError(result.ErrorClass, result.EndTime, result.Error)
+			continue
+		}
+		r.Successes++
+		roundTrips = append(roundTrips, result.ElapsedTime)
+	}
+
+	seconds := r.Duration.Seconds()
+	ws.Connect = calculateLatency(connectTimes)
+	if seconds > 0 {
+		ws.SentPerSecond = float64(ws.MessagesSent) / seconds
+		ws.ReceivedPerSecond = float64(ws.MessagesReceived) / seconds
+		r.Throughput.BytesSentPerSecond = float64(r.Throughput.TotalBytesSent) / seconds
+		r.Throughput.BytesReceivedPerSecond = float64(r.Throughput.TotalBytesReceived) / seconds
+	}
+
+	r.Latency = calculateLatency(roundTrips)
+	r.WebSocket = ws
+	return r
+}
@@ -0,0 +1,59 @@
+package report
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewWebSocketReport(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Second)
+
+	results := []Result{
+		{ElapsedTime: 10 * time.Millisecond, BytesSent: 5, BytesReceived: 5},
+		{ElapsedTime: 30 * time.Millisecond, BytesSent: 5, BytesReceived: 5},
+		{EndTime: end, BytesSent: 5, ErrorClass: ErrorClassConnClosed, Error: errors.New("close 1011")},
+	}
+	conns := []WebSocketConn{
+		{ConnectTime: 4 * time.Millisecond, Disconnect: DisconnectClientClosed, Received: 2},
+		{ConnectTime: 8 * time.Millisecond, Disconnect: "close 1011 (internal error)"},
+		{ConnectError: "403"},
+	}
+
+	r := NewWebSocketReport("ws://localhost/ws", start, end, results, conns)
+	if r.TotalRequests != 3 || r.Successes != 2 || r.Failures != 1 {
+		t.Errorf("expected 3 messages with 2 replies, got %d, %d, %d", r.TotalRequests, r.Successes, r.Failures)
+	}
+	if r.Latency.Max != "30ms" {
+		t.Errorf("expected latency over replies only, got %+v", r.Latency)
+	}
+	if r.ErrorBreakdown.TransportErrors[ErrorClassConnClosed].Count != 1 {
+		t.Errorf("expected a connection closed error, got %v", r.ErrorBreakdown.TransportErrors)
+	}
+
+	ws := r.WebSocket
+	if ws.Connections != 3 || ws.Established != 2 || ws.ConnectErrors["403"] != 1 {
+		t.Errorf("unexpected connection summary: %+v", ws)
+	}
+	if ws.Connect.Max != "8ms" || ws.MessagesReceived != 2 || ws.SentPerSecond != 1.5 || ws.ReceivedPerSecond != 1 {
+		t.Errorf("unexpected message summary: %+v", ws)
+	}
+	if ws.Disconnects[DisconnectClientClosed] != 1 || ws.Disconnects["close 1011 (internal error)"] != 1 {
+		t.Errorf("unexpected disconnects: %v", ws.Disconnects)
+	}
+
+	raw, err := ParseRaw(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"WebSocket:", "Messages Sent:           3 (1.50/sec)", "close 1011 (internal error):", "403:"} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("expected raw report to contain %q", want)
+		}
+	}
+	if strings.Contains(raw, "Status Code Breakdown") {
+		t.Error("expected no status code breakdown for a WebSocket test")
+	}
+}
@@ -0,0 +1,69 @@
+package rpc
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/logger"
+)
+
+// Config describes a gRPC load test. Requests calls are made at RPS, spread
+// over Connections channels to Target.
+type Config struct {
+	Target string
+	// Method is the full method name, package.Service/Method
+	Method string
+	// Data is the request message as JSON. Empty sends the default message.
+	Data string
+	// ProtoSet is a descriptor set file (protoc --descriptor_set_out
+	// --include_imports) describing the method. Without one the method is
+	// looked up with server reflection.
+	ProtoSet string
+	// Headers are sent as request metadata (Key1:Value1,Key2:Value2)
+	Headers     string
+	Requests    int
+	RPS         int
+	Connections int
+	// Timeout is the deadline of every call, including the whole stream of
+	// a server-streaming call
+	Timeout   time.Duration
+	Plaintext bool
+	Insecure  bool
+	Logger    *logger.Logger
+}
+
+// Validate checks the configuration
+func (c *Config) Validate() error {
+	if _, _, err := net.SplitHostPort(c.Target); err != nil {
+		return ErrInvalidTarget
+	}
+
+	if _, _, err := splitMethod(c.Method); err != nil {
+		return err
+	}
+
+	if c.Requests <= 0 || c.RPS <= 0 {
+		return ErrInvalidLoad
+	}
+
+	if c.Connections <= 0 {
+		return ErrInvalidConnections
+	}
+
+	if c.Timeout <= 0 {
+		return ErrInvalidTimeout
+	}
+
+	return nil
+}
+
+// splitMethod splits package.Service/Method, with an optional leading slash,
+// into the service and method names
+func splitMethod(fullMethod string) (string, string, error) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok || service == "" || method == "" || strings.Contains(method, "/") {
+		return "", "", ErrInvalidMethod
+	}
+	return service, method, nil
+}
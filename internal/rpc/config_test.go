@@ -0,0 +1,42 @@
+package rpc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	valid := Config{
+		Target:      "localhost:50051",
+		Method:      "helloworld.Greeter/SayHello",
+		Requests:    10,
+		RPS:         5,
+		Connections: 1,
+		Timeout:     time.Second,
+	}
+
+	for _, tc := range []struct {
+		name   string
+		modify func(*Config)
+		err    error
+	}{
+		{"valid", func(c *Config) {}, nil},
+		{"leading slash", func(c *Config) { c.Method = "/helloworld.Greeter/SayHello" }, nil},
+		{"no port", func(c *Config) { c.Target = "localhost" }, ErrInvalidTarget},
+		{"no method", func(c *Config) { c.Method = "helloworld.Greeter" }, ErrInvalidMethod},
+		{"empty service", func(c *Config) { c.Method = "/SayHello" }, ErrInvalidMethod},
+		{"extra segment", func(c *Config) { c.Method = "helloworld.Greeter/SayHello/x" }, ErrInvalidMethod},
+		{"no requests", func(c *Config) { c.Requests = 0 }, ErrInvalidLoad},
+		{"no rps", func(c *Config) { c.RPS = 0 }, ErrInvalidLoad},
+		{"no connections", func(c *Config) { c.Connections = 0 }, ErrInvalidConnections},
+		{"no timeout", func(c *Config) { c.Timeout = 0 }, ErrInvalidTimeout},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := valid
+			tc.modify(&cfg)
+			if err := cfg.Validate(); err != tc.err {
+				t.Errorf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// findMethod describes the configured method, from the descriptor set file
+// if there is one and with server reflection otherwise
+func findMethod(ctx context.Context, cfg Config, conn *grpc.ClientConn) (protoreflect.MethodDescriptor, error) {
+	service, method, err := splitMethod(cfg.Method)
+	if err != nil {
+		return nil, err
+	}
+
+	var files *protoregistry.Files
+	if cfg.ProtoSet != "" {
+		files, err = loadProtoSet(cfg.ProtoSet)
+	} else {
+		files, err = reflectFiles(ctx, conn, service)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMethodNotFound, cfg.Method)
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not a service", ErrMethodNotFound, service)
+	}
+	md := sd.Methods().ByName(protoreflect.Name(method))
+	if md == nil {
+		return nil, fmt.Errorf("%w: %s", ErrMethodNotFound, cfg.Method)
+	}
+
+	if md.IsStreamingClient() {
+		return nil, fmt.Errorf("%w: %s streams requests", ErrUnsupportedMethod, cfg.Method)
+	}
+	return md, nil
+}
+
+// loadProtoSet reads a serialized FileDescriptorSet
+func loadProtoSet(path string) (*protoregistry.Files, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(b, set); err != nil {
+		return nil, fmt.Errorf("error parsing descriptor set %s: %w", path, err)
+	}
+	return protodesc.NewFiles(set)
+}
+
+// fileFetcher asks a reflection service for the file that defines symbol,
+// or else the file with the given name, and returns the serialized file
+// descriptors of the answer
+type fileFetcher func(symbol, filename string) ([][]byte, error)
+
+// reflectFiles fetches the file that defines service and every file it
+// depends on. It uses the v1 reflection service and falls back to v1alpha
+// for servers that only offer that.
+func reflectFiles(ctx context.Context, conn *grpc.ClientConn, service string) (*protoregistry.Files, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var raw [][]byte
+	fetch, err := reflectionV1(ctx, conn)
+	if err == nil {
+		raw, err = fetch(service, "")
+	}
+	if status.Code(err) == codes.Unimplemented {
+		if fetch, err = reflectionV1Alpha(ctx, conn); err == nil {
+			raw, err = fetch(service, "")
+		}
+	}
+	if status.Code(err) == codes.NotFound {
+		return nil, fmt.Errorf("%w: the server does not know %s", ErrMethodNotFound, service)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching descriptors with server reflection: %w", err)
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+	add := func(raw [][]byte) error {
+		for _, b := range raw {
+			fd := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(b, fd); err != nil {
+				return err
+			}
+			if !seen[fd.GetName()] {
+				seen[fd.GetName()] = true
+				set.File = append(set.File, fd)
+			}
+		}
+		return nil
+	}
+	if err := add(raw); err != nil {
+		return nil, err
+	}
+
+	// servers leave out dependencies they consider already sent
+	for i := 0; i < len(set.File); i++ {
+		for _, dep := range set.File[i].GetDependency() {
+			if seen[dep] {
+				continue
+			}
+			raw, err := fetch("", dep)
+			if err != nil {
+				return nil, fmt.Errorf("error fetching %s with server reflection: %w", dep, err)
+			}
+			if err := add(raw); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return protodesc.NewFiles(set)
+}
+
+// reflectionV1 returns a fetcher backed by the v1 reflection service
+func reflectionV1(ctx context.Context, conn *grpc.ClientConn) (fileFetcher, error) {
+	stream, err := reflectionv1.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(symbol, filename string) ([][]byte, error) {
+		req := &reflectionv1.ServerReflectionRequest{
+			MessageRequest: &reflectionv1.ServerReflectionRequest_FileByFilename{FileByFilename: filename},
+		}
+		if symbol != "" {
+			req.MessageRequest = &reflectionv1.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: symbol}
+		}
+
+		// a failed send is explained by the status Recv returns
+		if err := stream.Send(req); err != nil && err != io.EOF {
+			return nil, err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if e := resp.GetErrorResponse(); e != nil {
+			return nil, status.Error(codes.Code(e.GetErrorCode()), e.GetErrorMessage())
+		}
+		return resp.GetFileDescriptorResponse().GetFileDescriptorProto(), nil
+	}, nil
+}
+
+// reflectionV1Alpha returns a fetcher backed by the v1alpha reflection
+// service
+func reflectionV1Alpha(ctx context.Context, conn *grpc.ClientConn) (fileFetcher, error) {
+	stream, err := reflectionv1alpha.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(symbol, filename string) ([][]byte, error) {
+		req := &reflectionv1alpha.ServerReflectionRequest{
+			MessageRequest: &reflectionv1alpha.ServerReflectionRequest_FileByFilename{FileByFilename: filename},
+		}
+		if symbol != "" {
+			req.MessageRequest = &reflectionv1alpha.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: symbol}
+		}
+
+		if err := stream.Send(req); err != nil && err != io.EOF {
+			return nil, err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if e := resp.GetErrorResponse(); e != nil {
+			return nil, status.Error(codes.Code(e.GetErrorCode()), e.GetErrorMessage())
+		}
+		return resp.GetFileDescriptorResponse().GetFileDescriptorProto(), nil
+	}, nil
+}
@@ -0,0 +1,14 @@
+package rpc
+
+import "errors"
+
+var (
+	ErrInvalidTarget      = errors.New("invalid gRPC target. Use host:port")
+	ErrInvalidMethod      = errors.New("invalid gRPC method. Use package.Service/Method")
+	ErrInvalidLoad        = errors.New("the number of requests and the RPS must be greater than zero")
+	ErrInvalidConnections = errors.New("the number of connections must be greater than zero")
+	ErrInvalidTimeout     = errors.New("the timeout must be greater than zero")
+	ErrInvalidData        = errors.New("the request data does not match the method's input message")
+	ErrMethodNotFound     = errors.New("gRPC method not found")
+	ErrUnsupportedMethod  = errors.New("only unary and server-streaming methods are supported")
+)
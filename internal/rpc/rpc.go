@@ -0,0 +1,191 @@
+// Package rpc load tests gRPC services. Methods are described by server
+// reflection or a descriptor set file, so requests are written as JSON and
+// no generated code is needed. Calls run through the worker pool like HTTP
+// requests and are reported by gRPC status.
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/config"
+	"github.com/rnemeth90/yahba/internal/report"
+	"github.com/rnemeth90/yahba/internal/util"
+	"github.com/rnemeth90/yahba/internal/worker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Run looks up the method, makes the calls and returns the report
+func Run(ctx context.Context, cfg Config) (report.Report, error) {
+	if err := cfg.Validate(); err != nil {
+		return report.Report{}, err
+	}
+
+	md := metadata.MD{}
+	if cfg.Headers != "" {
+		parsed, err := util.ParseHeaders(cfg.Headers)
+		if err != nil {
+			return report.Report{}, err
+		}
+		for _, h := range parsed {
+			md.Append(h.Key, h.Value)
+		}
+	}
+
+	creds := insecure.NewCredentials()
+	if !cfg.Plaintext {
+		creds = credentials.NewTLS(&tls.Config{InsecureSkipVerify: cfg.Insecure})
+	}
+
+	// every client conn keeps its own HTTP/2 connection
+	conns := make([]*grpc.ClientConn, cfg.Connections)
+	for i := range conns {
+		conn, err := grpc.NewClient(cfg.Target, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return report.Report{}, err
+		}
+		defer conn.Close()
+		conns[i] = conn
+	}
+
+	lookupCtx, cancel := context.WithTimeout(metadata.NewOutgoingContext(ctx, md), cfg.Timeout)
+	method, err := findMethod(lookupCtx, cfg, conns[0])
+	cancel()
+	if err != nil {
+		return report.Report{}, err
+	}
+
+	req := dynamicpb.NewMessage(method.Input())
+	if cfg.Data != "" {
+		if err := protojson.Unmarshal([]byte(cfg.Data), req); err != nil {
+			return report.Report{}, fmt.Errorf("%w: %v", ErrInvalidData, err)
+		}
+	}
+
+	fullMethod := "/" + string(method.Parent().FullName()) + "/" + string(method.Name())
+	c := &caller{
+		conns:      conns,
+		method:     method,
+		fullMethod: fullMethod,
+		req:        req,
+		reqSize:    proto.Size(req),
+		md:         md,
+		timeout:    cfg.Timeout,
+	}
+
+	wcfg := config.Config{
+		URL:      cfg.Target,
+		Method:   cfg.Method,
+		Requests: cfg.Requests,
+		RPS:      cfg.RPS,
+		Logger:   cfg.Logger,
+	}
+
+	cfg.Logger.Info("Calling %s on %s over %d connections", fullMethod, cfg.Target, cfg.Connections)
+	start := time.Now()
+	results := worker.StartInvoker(ctx, wcfg, worker.CreateJobs(wcfg), c.invoke)
+	r := worker.ProcessResults(wcfg, results)
+	end := time.Now()
+
+	r.Host = cfg.Target
+	r.Method = cfg.Method
+	r.StartTime = start.Format(time.RFC3339Nano)
+	r.EndTime = end.Format(time.RFC3339Nano)
+	r.Duration = end.Sub(start)
+	r.CalculateGRPC(method.IsStreamingServer())
+	return r, nil
+}
+
+// caller makes the calls of a test, round robin over the connections
+type caller struct {
+	conns      []*grpc.ClientConn
+	method     protoreflect.MethodDescriptor
+	fullMethod string
+	// req is shared by every call, which only ever reads it
+	req     proto.Message
+	reqSize int
+	md      metadata.MD
+	timeout time.Duration
+}
+
+// invoke makes a single call and records its status and the messages it
+// received
+func (c *caller) invoke(ctx context.Context, job worker.Job) report.Result {
+	conn := c.conns[job.ID%len(c.conns)]
+
+	ctx, cancel := context.WithTimeout(metadata.NewOutgoingContext(ctx, c.md), c.timeout)
+	defer cancel()
+
+	result := report.Result{
+		StartTime: time.Now(),
+		Method:    job.Method,
+		TargetURL: job.Host,
+		BytesSent: c.reqSize,
+	}
+
+	var err error
+	if c.method.IsStreamingServer() {
+		err = c.stream(ctx, conn, &result)
+	} else {
+		resp := dynamicpb.NewMessage(c.method.Output())
+		if err = conn.Invoke(ctx, c.fullMethod, c.req, resp); err == nil {
+			result.Messages = 1
+			result.BytesReceived = proto.Size(resp)
+		}
+	}
+
+	result.EndTime = time.Now()
+	result.ElapsedTime = result.EndTime.Sub(result.StartTime)
+
+	code := status.Code(err)
+	result.GRPCStatus = report.GRPCStatus(uint32(code))
+	if err != nil {
+		result.Error = err
+		result.Timeout = code == codes.DeadlineExceeded
+	}
+	return result
+}
+
+// stream makes a server-streaming call and reads every message until the
+// server ends the stream
+func (c *caller) stream(ctx context.Context, conn *grpc.ClientConn, result *report.Result) error {
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, c.fullMethod)
+	if err != nil {
+		return err
+	}
+
+	// a failed send is explained by the status RecvMsg returns
+	if err := stream.SendMsg(c.req); err != nil && err != io.EOF {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+
+	for {
+		resp := dynamicpb.NewMessage(c.method.Output())
+		if err := stream.RecvMsg(resp); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if result.Messages == 0 {
+			result.FirstMessageTime = time.Since(result.StartTime)
+		}
+		result.Messages++
+		result.BytesReceived += proto.Size(resp)
+	}
+}
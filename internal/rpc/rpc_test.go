@@ -0,0 +1,265 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// echoFile describes yahba.test.Echo. Say answers with the request's text,
+// Count streams it count times, and both fail with the request's code when
+// it is set.
+var echoFile = &descriptorpb.FileDescriptorProto{
+	Name:    proto.String("yahba/test/echo.proto"),
+	Package: proto.String("yahba.test"),
+	Syntax:  proto.String("proto3"),
+	MessageType: []*descriptorpb.DescriptorProto{
+		{Name: proto.String("Request"), Field: []*descriptorpb.FieldDescriptorProto{
+			field("text", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			field("count", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32),
+			field("code", 3, descriptorpb.FieldDescriptorProto_TYPE_INT32),
+		}},
+		{Name: proto.String("Reply"), Field: []*descriptorpb.FieldDescriptorProto{
+			field("text", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+		}},
+	},
+	Service: []*descriptorpb.ServiceDescriptorProto{{
+		Name: proto.String("Echo"),
+		Method: []*descriptorpb.MethodDescriptorProto{
+			{Name: proto.String("Say"), InputType: proto.String(".yahba.test.Request"), OutputType: proto.String(".yahba.test.Reply")},
+			{Name: proto.String("Count"), InputType: proto.String(".yahba.test.Request"), OutputType: proto.String(".yahba.test.Reply"), ServerStreaming: proto.Bool(true)},
+			{Name: proto.String("Chat"), InputType: proto.String(".yahba.test.Request"), OutputType: proto.String(".yahba.test.Reply"), ClientStreaming: proto.Bool(true), ServerStreaming: proto.Bool(true)},
+		},
+	}},
+}
+
+func field(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+	return &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(name),
+		Number:   proto.Int32(number),
+		Type:     typ.Enum(),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+	}
+}
+
+// echoServer serves yahba.test.Echo, with the given reflection services
+// ("v1", "v1alpha"), and returns its address
+func echoServer(t *testing.T, reflectionServices ...string) string {
+	t.Helper()
+	file, err := protodesc.NewFile(echoFile, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := &protoregistry.Files{}
+	if err := files.RegisterFile(file); err != nil {
+		t.Fatal(err)
+	}
+
+	service := file.Services().Get(0)
+	request := file.Messages().ByName("Request")
+	reply := file.Messages().ByName("Reply")
+	text := request.Fields().ByName("text")
+
+	// read decodes a request and returns its error status, if it asks for one
+	read := func(dec func(any) error) (*dynamicpb.Message, error) {
+		req := dynamicpb.NewMessage(request)
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		if code := req.Get(request.Fields().ByName("code")).Int(); code != 0 {
+			return nil, status.Error(codes.Code(code), "requested")
+		}
+		return req, nil
+	}
+	answer := func(req *dynamicpb.Message) *dynamicpb.Message {
+		resp := dynamicpb.NewMessage(reply)
+		resp.Set(reply.Fields().ByName("text"), req.Get(text))
+		return resp
+	}
+
+	s := grpc.NewServer()
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: string(service.FullName()),
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Say",
+			Handler: func(_ any, _ context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				req, err := read(dec)
+				if err != nil {
+					return nil, err
+				}
+				return answer(req), nil
+			},
+		}},
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Count",
+			ServerStreams: true,
+			Handler: func(_ any, stream grpc.ServerStream) error {
+				req, err := read(stream.RecvMsg)
+				if err != nil {
+					return err
+				}
+				for i := int64(0); i < req.Get(request.Fields().ByName("count")).Int(); i++ {
+					if err := stream.SendMsg(answer(req)); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		}},
+	}, struct{}{})
+
+	opts := reflection.ServerOptions{Services: s, DescriptorResolver: files}
+	for _, version := range reflectionServices {
+		switch version {
+		case "v1":
+			reflectionv1.RegisterServerReflectionServer(s, reflection.NewServerV1(opts))
+		case "v1alpha":
+			reflectionv1alpha.RegisterServerReflectionServer(s, reflection.NewServer(opts))
+		}
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	return lis.Addr().String()
+}
+
+func testConfig(target, method, data string) Config {
+	return Config{
+		Target:      target,
+		Method:      method,
+		Data:        data,
+		Requests:    4,
+		RPS:         20,
+		Connections: 2,
+		Timeout:     5 * time.Second,
+		Plaintext:   true,
+		Logger:      logger.New("error", "stdout", false),
+	}
+}
+
+func TestRunUnary(t *testing.T) {
+	target := echoServer(t, "v1", "v1alpha")
+
+	r, err := Run(context.Background(), testConfig(target, "yahba.test.Echo/Say", `{"text":"hello"}`))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, 4, r.TotalRequests)
+	assert.Equal(t, 4, r.Successes)
+	assert.Equal(t, 4, r.StatusCodes["OK"].Count)
+	assert.Equal(t, "yahba.test.Echo/Say", r.Method)
+	assert.Equal(t, 4, r.GRPC.MessagesReceived)
+	assert.False(t, r.GRPC.Streaming)
+	assert.Greater(t, r.Throughput.TotalBytesReceived, 0)
+}
+
+func TestRunStatusCodes(t *testing.T) {
+	target := echoServer(t, "v1")
+
+	r, err := Run(context.Background(), testConfig(target, "/yahba.test.Echo/Say", `{"code":5}`))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, 4, r.Failures)
+	assert.Equal(t, 4, r.StatusCodes["NOT_FOUND"].Count)
+	assert.Equal(t, 0, r.GRPC.MessagesReceived)
+}
+
+func TestRunServerStreaming(t *testing.T) {
+	target := echoServer(t, "v1")
+
+	r, err := Run(context.Background(), testConfig(target, "yahba.test.Echo/Count", `{"text":"tick","count":3}`))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, 4, r.Successes)
+	assert.True(t, r.GRPC.Streaming)
+	assert.Equal(t, 12, r.GRPC.MessagesReceived)
+	assert.Equal(t, 3.0, r.GRPC.MessagesPerCall)
+	if assert.NotNil(t, r.GRPC.FirstMessage) {
+		assert.NotEmpty(t, r.GRPC.FirstMessage.Max)
+	}
+	for _, result := range r.Results {
+		assert.Equal(t, 3, result.Messages)
+		assert.LessOrEqual(t, result.FirstMessageTime, result.ElapsedTime)
+	}
+}
+
+func TestRunDescriptorSources(t *testing.T) {
+	set, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{echoFile}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	protoset := filepath.Join(t.TempDir(), "echo.protoset")
+	if err := os.WriteFile(protoset, set, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		reflection []string
+		protoset   string
+	}{
+		{"v1 reflection", []string{"v1"}, ""},
+		{"v1alpha reflection", []string{"v1alpha"}, ""},
+		{"descriptor set", nil, protoset},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig(echoServer(t, tc.reflection...), "yahba.test.Echo/Say", `{"text":"hello"}`)
+			cfg.ProtoSet = tc.protoset
+
+			r, err := Run(context.Background(), cfg)
+			if assert.NoError(t, err) {
+				assert.Equal(t, 4, r.Successes)
+			}
+		})
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	target := echoServer(t, "v1")
+
+	for _, tc := range []struct {
+		method string
+		data   string
+		err    error
+	}{
+		{"yahba.test.Echo/Shout", "", ErrMethodNotFound},
+		{"yahba.test.Missing/Say", "", ErrMethodNotFound},
+		{"yahba.test.Echo/Chat", "", ErrUnsupportedMethod},
+		{"yahba.test.Echo/Say", `{"volume":11}`, ErrInvalidData},
+	} {
+		_, err := Run(context.Background(), testConfig(target, tc.method, tc.data))
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%s with %q: expected %v, got %v", tc.method, tc.data, tc.err, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/logger"
+)
+
+const (
+	ServiceFixed       = "fixed"
+	ServiceExponential = "exponential"
+)
+
+// capacityLimiter simulates a service with a fixed number of workers and a
+// bounded queue. Each admitted request holds a worker for the service time;
+// requests that arrive when every worker is busy and the queue is full are
+// shed. With exponential service times this is an M/M/c/K queue, so expected
+// throughput and rejection rates can be computed analytically.
+type capacityLimiter struct {
+	cfg     CapacityConfig
+	workers chan struct{}
+	limit   int64
+	inside  atomic.Int64
+	logger  *logger.Logger
+}
+
+func newCapacityLimiter(cfg CapacityConfig, l *logger.Logger) (*capacityLimiter, error) {
+	if cfg.Workers < 0 || cfg.QueueSize < 0 || cfg.ServiceTime < 0 {
+		return nil, ErrInvalidCapacity
+	}
+
+	switch cfg.ServiceDistribution {
+	case "":
+		cfg.ServiceDistribution = ServiceFixed
+	case ServiceFixed, ServiceExponential:
+	default:
+		return nil, ErrInvalidServiceDistribution
+	}
+
+	if cfg.ShedStatus == 0 {
+		cfg.ShedStatus = http.StatusServiceUnavailable
+	}
+	if cfg.ShedStatus < 100 || cfg.ShedStatus > 599 {
+		return nil, ErrInvalidCapacity
+	}
+
+	return &capacityLimiter{
+		cfg:     cfg,
+		workers: make(chan struct{}, cfg.Workers),
+		limit:   int64(cfg.Workers + cfg.QueueSize),
+		logger:  l,
+	}, nil
+}
+
+// serviceTime samples how long a request holds its worker
+func (c *capacityLimiter) serviceTime() time.Duration {
+	if c.cfg.ServiceDistribution == ServiceExponential {
+		return time.Duration(rand.ExpFloat64() * float64(c.cfg.ServiceTime))
+	}
+	return c.cfg.ServiceTime
+}
+
+// wrap admits, queues or sheds each request before passing it to next
+func (c *capacityLimiter) wrap(next http.Handler) http.Handler {
+	if c.cfg.Workers == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.inside.Add(1) > c.limit {
+			c.inside.Add(-1)
+			c.logger.Debug("Shedding %s %s: %d workers busy and queue of %d full", r.Method, r.URL.Path, c.cfg.Workers, c.cfg.QueueSize)
+			if c.cfg.ServiceTime > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(max(1, int(c.cfg.ServiceTime.Seconds()))))
+			}
+			http.Error(w, http.StatusText(c.cfg.ShedStatus), c.cfg.ShedStatus)
+			return
+		}
+		defer c.inside.Add(-1)
+
+		// wait in the queue for a free worker
+		select {
+		case <-r.Context().Done():
+			return
+		case c.workers <- struct{}{}:
+		}
+		defer func() { <-c.workers }()
+
+		if !sleep(r, c.serviceTime()) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/config"
+	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/rnemeth90/yahba/internal/worker"
+	"github.com/stretchr/testify/assert"
+)
+
+// burst sends n concurrent requests and counts the responses by status
+func burst(t *testing.T, url string, n int) map[int]int {
+	t.Helper()
+	var mu sync.Mutex
+	statuses := make(map[int]int)
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := http.Get(url)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+
+			mu.Lock()
+			statuses[resp.StatusCode]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return statuses
+}
+
+func TestCapacityShedsExcess(t *testing.T) {
+	ts := newTestServer(t, Config{Capacity: CapacityConfig{Workers: 2, ServiceTime: 300 * time.Millisecond}})
+
+	statuses := burst(t, ts.URL+"/test", 10)
+	assert.Equal(t, 2, statuses[http.StatusOK])
+	assert.Equal(t, 8, statuses[http.StatusServiceUnavailable])
+}
+
+func TestCapacityQueues(t *testing.T) {
+	ts := newTestServer(t, Config{Capacity: CapacityConfig{Workers: 2, QueueSize: 3, ServiceTime: 300 * time.Millisecond, ShedStatus: http.StatusTooManyRequests}})
+
+	statuses := burst(t, ts.URL+"/test", 10)
+	assert.Equal(t, 5, statuses[http.StatusOK])
+	assert.Equal(t, 5, statuses[http.StatusTooManyRequests])
+}
+
+func TestCapacityValidation(t *testing.T) {
+	l := logger.New("error", "stdout", false)
+
+	_, err := newCapacityLimiter(CapacityConfig{Workers: -1}, l)
+	assert.ErrorIs(t, err, ErrInvalidCapacity)
+
+	_, err = newCapacityLimiter(CapacityConfig{Workers: 1, ShedStatus: 42}, l)
+	assert.ErrorIs(t, err, ErrInvalidCapacity)
+
+	_, err = newCapacityLimiter(CapacityConfig{Workers: 1, ServiceDistribution: "poisson"}, l)
+	assert.ErrorIs(t, err, ErrInvalidServiceDistribution)
+}
+
+// TestCapacityMatchesYahba drives the simulated service past its analytical
+// capacity with the worker pool and checks the report agrees
+func TestCapacityMatchesYahba(t *testing.T) {
+	// 2 workers * (1s / 100ms) = 20 requests per second, no queue
+	ts := newTestServer(t, Config{Capacity: CapacityConfig{Workers: 2, ServiceTime: 100 * time.Millisecond}})
+
+	cfg := config.Config{
+		URL:      ts.URL + "/test",
+		Method:   "GET",
+		Requests: 40,
+		RPS:      40,
+		Timeout:  5,
+		Logger:   logger.New("error", "stdout", false),
+	}
+
+	results, err := worker.Start(context.Background(), cfg, worker.CreateJobs(cfg), worker.DefaultFactory)
+	assert.NoError(t, err)
+	r := worker.ProcessResults(cfg, results)
+
+	// offered 40 RPS for one second against a capacity of 20 RPS
+	assert.Equal(t, 40, r.TotalRequests)
+	assert.InDelta(t, 20, r.StatusCodes["200"].Count, 5)
+	assert.InDelta(t, 20, r.StatusCodes["503"].Count, 5)
+}
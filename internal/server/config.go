@@ -1,5 +1,59 @@
 package server
 
+import "time"
+
 type Config struct {
-	Port string
+	Port   string
+	Faults FaultConfig
+	// RoutesFile is an optional YAML file of routes, reloaded when it changes
+	RoutesFile string
+	// ReloadInterval is how often RoutesFile is checked for changes
+	ReloadInterval time.Duration
+
+	// TLS serves HTTPS (and HTTP/2 via ALPN). A self-signed certificate is
+	// generated unless CertFile and KeyFile are set.
+	TLS      bool
+	CertFile string
+	KeyFile  string
+	// ClientCAFile requires clients to present a certificate signed by this CA
+	ClientCAFile string
+	// RequireClientCert requires clients to present any certificate
+	RequireClientCert bool
+	// H2C serves HTTP/2 over cleartext alongside HTTP/1.1
+	H2C bool
+	// HTTP3 also serves HTTP/3 over QUIC on the same UDP port. It implies TLS.
+	HTTP3 bool
+
+	Capacity CapacityConfig
+}
+
+// CapacityConfig simulates a service with limited concurrency. The server
+// can process Workers requests at once, queues up to QueueSize more and
+// sheds the rest with ShedStatus. Zero workers disables the simulation.
+type CapacityConfig struct {
+	Workers     int
+	QueueSize   int
+	ServiceTime time.Duration
+	// ServiceDistribution is fixed or exponential
+	ServiceDistribution string
+	ShedStatus          int
+}
+
+// FaultConfig controls how the test server misbehaves. Rates are percentages
+// of all requests, from 0 to 100.
+type FaultConfig struct {
+	// LatencyDistribution is one of fixed, normal or longtail
+	LatencyDistribution string
+	// Latency is the fixed delay, the mean of the normal distribution or the
+	// median of the long-tail distribution
+	Latency time.Duration
+	// LatencyJitter is the standard deviation of the normal distribution, or
+	// the spread of the long-tail distribution relative to Latency
+	LatencyJitter time.Duration
+	// ErrorRates is a comma-separated list of status:percent pairs, e.g. 500:5,503:2.5
+	ErrorRates string
+	ResetRate  float64
+	HangRate   float64
+	// Seed makes the injected faults reproducible when non-zero
+	Seed uint64
 }
@@ -3,5 +3,15 @@ package server
 import "errors"
 
 var (
-	ErrInvalidPort = errors.New("invalid port number")
+	ErrInvalidPort                = errors.New("invalid port number")
+	ErrInvalidLatencyDistribution = errors.New("invalid latency distribution. Supported distributions are fixed, normal, longtail")
+	ErrInvalidErrorRates          = errors.New("invalid error rates format. Expected a comma-separated list of 'status:percent' pairs")
+	ErrInvalidFaultRate           = errors.New("fault rates must be between 0 and 100 and add up to at most 100")
+	ErrInvalidRouteFile           = errors.New("invalid route file")
+	ErrInvalidCertificate         = errors.New("invalid certificate. Both a certificate and a key file must be provided")
+	ErrInvalidClientCA            = errors.New("no valid certificates found in the client CA file")
+	ErrInvalidCapacity            = errors.New("capacity workers, queue size and service time must not be negative, and the shed status must be a valid HTTP status")
+	ErrInvalidServiceDistribution = errors.New("invalid service time distribution. Supported distributions are fixed, exponential")
+	ErrStatsUnavailable           = errors.New("server stats unavailable. Is the target a yahba test server?")
+	ErrConflictingTLSOptions      = errors.New("h2c serves HTTP/2 without TLS and cannot be combined with TLS or HTTP/3 options")
 )
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/logger"
+)
+
+const (
+	LatencyFixed    = "fixed"
+	LatencyNormal   = "normal"
+	LatencyLongTail = "longtail"
+)
+
+type errorRate struct {
+	status  int
+	percent float64
+}
+
+// faultInjector wraps handlers with configurable latency, errors, resets and hangs
+type faultInjector struct {
+	cfg        FaultConfig
+	errorRates []errorRate
+	logger     *logger.Logger
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func newFaultInjector(cfg FaultConfig, l *logger.Logger) (*faultInjector, error) {
+	switch cfg.LatencyDistribution {
+	case "":
+		cfg.LatencyDistribution = LatencyFixed
+	case LatencyFixed, LatencyNormal, LatencyLongTail:
+	default:
+		return nil, ErrInvalidLatencyDistribution
+	}
+
+	rates, err := parseErrorRates(cfg.ErrorRates)
+	if err != nil {
+		return nil, err
+	}
+
+	total := cfg.ResetRate + cfg.HangRate
+	for _, r := range rates {
+		total += r.percent
+	}
+	if cfg.ResetRate < 0 || cfg.HangRate < 0 || total > 100 {
+		return nil, ErrInvalidFaultRate
+	}
+
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+
+	return &faultInjector{
+		cfg:        cfg,
+		errorRates: rates,
+		logger:     l,
+		rng:        rand.New(rand.NewPCG(seed, seed)),
+	}, nil
+}
+
+// parseErrorRates parses a list like "500:5,503:2.5" into status codes and percentages
+func parseErrorRates(raw string) ([]errorRate, error) {
+	var rates []errorRate
+	if strings.TrimSpace(raw) == "" {
+		return rates, nil
+	}
+
+	for _, pair := range strings.Split(raw, ",") {
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 {
+			return nil, ErrInvalidErrorRates
+		}
+
+		status, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil || status < 100 || status > 599 {
+			return nil, ErrInvalidErrorRates
+		}
+
+		percent, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, ErrInvalidErrorRates
+		}
+
+		rates = append(rates, errorRate{status: status, percent: percent})
+	}
+
+	sort.Slice(rates, func(i, j int) bool { return rates[i].status < rates[j].status })
+	return rates, nil
+}
+
+// roll returns a random percentage in [0, 100)
+func (f *faultInjector) roll() float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rng.Float64() * 100
+}
+
+// latency samples a delay from the configured distribution
+func (f *faultInjector) latency() time.Duration {
+	if f.cfg.Latency <= 0 {
+		return 0
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch f.cfg.LatencyDistribution {
+	case LatencyNormal:
+		d := float64(f.cfg.Latency) + f.rng.NormFloat64()*float64(f.cfg.LatencyJitter)
+		return time.Duration(math.Max(0, d))
+	case LatencyLongTail:
+		// log-normal with the configured median; most requests are near the
+		// median but a few are many times slower
+		sigma := 1.0
+		if f.cfg.LatencyJitter > 0 {
+			sigma = float64(f.cfg.LatencyJitter) / float64(f.cfg.Latency)
+		}
+		return time.Duration(float64(f.cfg.Latency) * math.Exp(f.rng.NormFloat64()*sigma))
+	default:
+		return f.cfg.Latency
+	}
+}
+
+// wrap applies the configured faults before passing the request to next
+func (f *faultInjector) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		roll := f.roll()
+
+		if roll < f.cfg.HangRate {
+			f.logger.Debug("Hanging request %s %s", r.Method, r.URL.Path)
+			<-r.Context().Done()
+			return
+		}
+		roll -= f.cfg.HangRate
+
+		if !sleep(r, f.latency()) {
+			return
+		}
+
+		if roll < f.cfg.ResetRate {
+			f.logger.Debug("Resetting connection for %s %s", r.Method, r.URL.Path)
+			resetConnection(w)
+			return
+		}
+		roll -= f.cfg.ResetRate
+
+		for _, e := range f.errorRates {
+			if roll < e.percent {
+				f.logger.Debug("Injecting %d for %s %s", e.status, r.Method, r.URL.Path)
+				http.Error(w, http.StatusText(e.status), e.status)
+				return
+			}
+			roll -= e.percent
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// resetConnection aborts the connection with a TCP RST where possible
+func resetConnection(w http.ResponseWriter) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		// HTTP/2 streams can't be hijacked; aborting the handler resets the stream
+		panic(http.ErrAbortHandler)
+	}
+
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetLinger(0)
+	}
+	conn.Close()
+}
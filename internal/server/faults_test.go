@@ -0,0 +1,113 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseErrorRates(t *testing.T) {
+	rates, err := parseErrorRates("503:2.5, 500:5")
+	assert.NoError(t, err)
+	assert.Equal(t, []errorRate{{status: 500, percent: 5}, {status: 503, percent: 2.5}}, rates)
+
+	rates, err = parseErrorRates("")
+	assert.NoError(t, err)
+	assert.Empty(t, rates)
+
+	for _, raw := range []string{"500", "abc:5", "500:x", "700:5", "500:101"} {
+		_, err := parseErrorRates(raw)
+		assert.ErrorIs(t, err, ErrInvalidErrorRates, raw)
+	}
+}
+
+func TestNewFaultInjectorValidation(t *testing.T) {
+	l := logger.New("error", "stdout", false)
+
+	_, err := newFaultInjector(FaultConfig{LatencyDistribution: "uniform"}, l)
+	assert.ErrorIs(t, err, ErrInvalidLatencyDistribution)
+
+	_, err = newFaultInjector(FaultConfig{ErrorRates: "500:60", ResetRate: 50}, l)
+	assert.ErrorIs(t, err, ErrInvalidFaultRate)
+
+	_, err = newFaultInjector(FaultConfig{HangRate: -1}, l)
+	assert.ErrorIs(t, err, ErrInvalidFaultRate)
+}
+
+func TestLatencyDistributions(t *testing.T) {
+	l := logger.New("error", "stdout", false)
+
+	fixed, err := newFaultInjector(FaultConfig{Latency: 10 * time.Millisecond}, l)
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Millisecond, fixed.latency())
+
+	normal, err := newFaultInjector(FaultConfig{LatencyDistribution: LatencyNormal, Latency: 100 * time.Millisecond, LatencyJitter: 10 * time.Millisecond, Seed: 1}, l)
+	assert.NoError(t, err)
+	var total time.Duration
+	for i := 0; i < 1000; i++ {
+		d := normal.latency()
+		assert.GreaterOrEqual(t, d, time.Duration(0))
+		total += d
+	}
+	assert.InDelta(t, float64(100*time.Millisecond), float64(total/1000), float64(5*time.Millisecond))
+
+	longTail, err := newFaultInjector(FaultConfig{LatencyDistribution: LatencyLongTail, Latency: 10 * time.Millisecond, Seed: 1}, l)
+	assert.NoError(t, err)
+	var slowest time.Duration
+	for i := 0; i < 1000; i++ {
+		slowest = max(slowest, longTail.latency())
+	}
+	assert.Greater(t, slowest, 50*time.Millisecond)
+}
+
+func TestInjectedErrors(t *testing.T) {
+	ts := newTestServer(t, Config{Faults: FaultConfig{ErrorRates: "503:100"}})
+
+	resp, err := http.Get(ts.URL + "/test")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
+
+func TestInjectedErrorRate(t *testing.T) {
+	ts := newTestServer(t, Config{Faults: FaultConfig{ErrorRates: "500:30", Seed: 42}})
+
+	failures := 0
+	for i := 0; i < 500; i++ {
+		resp, err := http.Get(ts.URL + "/test")
+		assert.NoError(t, err)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusInternalServerError {
+			failures++
+		}
+	}
+	assert.InDelta(t, 150, failures, 40)
+}
+
+func TestInjectedReset(t *testing.T) {
+	ts := newTestServer(t, Config{Faults: FaultConfig{ResetRate: 100}})
+
+	_, err := http.Get(ts.URL + "/test")
+	assert.Error(t, err)
+}
+
+func TestInjectedHang(t *testing.T) {
+	ts := newTestServer(t, Config{Faults: FaultConfig{HangRate: 100}})
+
+	client := &http.Client{Timeout: 100 * time.Millisecond}
+	_, err := client.Get(ts.URL + "/test")
+	assert.Error(t, err)
+}
+
+func TestInjectedLatency(t *testing.T) {
+	ts := newTestServer(t, Config{Faults: FaultConfig{Latency: 50 * time.Millisecond}})
+
+	start := time.Now()
+	resp, err := http.Get(ts.URL + "/test")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
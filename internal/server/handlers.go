@@ -1,15 +1,272 @@
 package server
 
 import (
+	"fmt"
 	"net/http"
-
-	"github.com/rnemeth90/yahba/internal/logger"
+	"strconv"
+	"time"
 )
 
-func testHandler(w http.ResponseWriter, r *http.Request) {
-	logger := logger.New("debug", "stdout", false)
-	logger.Info("Hello sent")
+// registerHandlers adds the built-in routes to mux
+func (s *Server) registerHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/test", s.testHandler)
+	mux.HandleFunc("/chunked", s.chunkedHandler)
+	mux.HandleFunc("/drip", s.dripHandler)
+	mux.HandleFunc("/bytes/{n}", s.bytesHandler)
+	mux.HandleFunc("/delay/headers", s.delayHeadersHandler)
+	mux.HandleFunc("/delay/body", s.delayBodyHandler)
+	mux.HandleFunc("/sse", s.sseHandler)
+	mux.HandleFunc("/ws", s.wsEchoHandler)
+}
+
+func (s *Server) testHandler(w http.ResponseWriter, r *http.Request) {
+	s.Logger.Info("Hello sent")
 	w.WriteHeader(http.StatusOK)
 }
 
+// chunkedHandler streams count chunks of size bytes using chunked transfer
+// encoding, flushing each one and waiting interval between them.
+//
+//	/chunked?size=1024&count=10&interval=100ms
+func (s *Server) chunkedHandler(w http.ResponseWriter, r *http.Request) {
+	size, err1 := intParam(r, "size", 1024)
+	count, err2 := intParam(r, "count", 10)
+	interval, err3 := durationParam(r, "interval", 100*time.Millisecond)
+	if err := firstError(err1, err2, err3); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+
+	for i := 0; i < count; i++ {
+		if i > 0 && !sleep(r, interval) {
+			return
+		}
+		if err := writeBytes(w, int64(size)); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// dripHandler feeds bytes to the client one at a time, spread evenly over
+// duration, after an optional delay before the headers.
+//
+//	/drip?bytes=100&duration=5s&delay=0s
+func (s *Server) dripHandler(w http.ResponseWriter, r *http.Request) {
+	n, err1 := intParam(r, "bytes", 100)
+	duration, err2 := durationParam(r, "duration", 5*time.Second)
+	delay, err3 := durationParam(r, "delay", 0)
+	if err := firstError(err1, err2, err3); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	if !sleep(r, delay) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(n))
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var interval time.Duration
+	if n > 0 {
+		interval = duration / time.Duration(n)
+	}
+	for i := 0; i < n; i++ {
+		if i > 0 && !sleep(r, interval) {
+			return
+		}
+		if _, err := w.Write([]byte{'*'}); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// bytesHandler returns a generated payload of n bytes with a Content-Length
+//
+//	/bytes/1048576
+func (s *Server) bytesHandler(w http.ResponseWriter, r *http.Request) {
+	n, err := strconv.ParseInt(r.PathValue("n"), 10, 64)
+	if err != nil || n < 0 {
+		http.Error(w, fmt.Sprintf("invalid byte count %q", r.PathValue("n")), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(n, 10))
+	w.WriteHeader(http.StatusOK)
+	writeBytes(w, n)
+}
+
+// delayHeadersHandler waits before sending anything, so the client sees a
+// slow time to first byte
+//
+//	/delay/headers?delay=1s
+func (s *Server) delayHeadersHandler(w http.ResponseWriter, r *http.Request) {
+	delay, err := durationParam(r, "delay", time.Second)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !sleep(r, delay) {
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// delayBodyHandler sends the headers immediately and waits before sending
+// the body, so the client sees a fast first byte but a slow response
+//
+//	/delay/body?delay=1s
+func (s *Server) delayBodyHandler(w http.ResponseWriter, r *http.Request) {
+	delay, err := durationParam(r, "delay", time.Second)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
 
+	w.Header().Set("Content-Length", "2")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	if !sleep(r, delay) {
+		return
+	}
+	w.Write([]byte("ok"))
+}
+
+// sseHandler streams count Server-Sent Events, one every interval
+//
+//	/sse?count=10&interval=1s
+func (s *Server) sseHandler(w http.ResponseWriter, r *http.Request) {
+	count, err1 := intParam(r, "count", 10)
+	interval, err2 := durationParam(r, "interval", time.Second)
+	if err := firstError(err1, err2); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for i := 0; i < count; i++ {
+		if i > 0 && !sleep(r, interval) {
+			return
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: message\ndata: {\"seq\": %d, \"time\": %q}\n\n", i, i, time.Now().Format(time.RFC3339Nano)); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// filler is written repeatedly to generate large payloads
+var filler = func() []byte {
+	b := make([]byte, 32*1024)
+	for i := range b {
+		b[i] = 'a' + byte(i%26)
+	}
+	return b
+}()
+
+// writeBytes writes n generated bytes to w
+func writeBytes(w http.ResponseWriter, n int64) error {
+	for n > 0 {
+		chunk := filler
+		if n < int64(len(chunk)) {
+			chunk = chunk[:n]
+		}
+		written, err := w.Write(chunk)
+		if err != nil {
+			return err
+		}
+		n -= int64(written)
+	}
+	return nil
+}
+
+// sleep waits for d, returning false if the client went away first
+func sleep(r *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-r.Context().Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func intParam(r *http.Request, name string, def int) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return def, nil
+	}
+
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, raw)
+	}
+	return v, nil
+}
+
+func durationParam(r *http.Request, name string, def time.Duration) (time.Duration, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return def, nil
+	}
+
+	v, err := time.ParseDuration(raw)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, raw)
+	}
+	return v, nil
+}
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
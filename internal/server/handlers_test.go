@@ -0,0 +1,111 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunkedHandler(t *testing.T) {
+	ts := newTestServer(t, Config{})
+
+	resp, err := http.Get(ts.URL + "/chunked?size=100&count=5&interval=10ms")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Len(t, body, 500)
+}
+
+func TestDripHandler(t *testing.T) {
+	ts := newTestServer(t, Config{})
+
+	start := time.Now()
+	resp, err := http.Get(ts.URL + "/drip?bytes=5&duration=100ms")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "*****", string(body))
+	assert.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+}
+
+func TestBytesHandler(t *testing.T) {
+	ts := newTestServer(t, Config{})
+
+	resp, err := http.Get(ts.URL + "/bytes/100000")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, int64(100000), resp.ContentLength)
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Len(t, body, 100000)
+
+	status, _, _ := get(t, http.MethodGet, ts.URL+"/bytes/-1")
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestDelayHandlers(t *testing.T) {
+	ts := newTestServer(t, Config{})
+
+	// delayed headers: the response itself arrives late
+	start := time.Now()
+	resp, err := http.Get(ts.URL + "/delay/headers?delay=100ms")
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	resp.Body.Close()
+
+	// delayed body: the headers arrive straight away, the body late
+	start = time.Now()
+	resp, err = http.Get(ts.URL + "/delay/body?delay=100ms")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", string(body))
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestSSEHandler(t *testing.T) {
+	ts := newTestServer(t, Config{})
+
+	resp, err := http.Get(ts.URL + "/sse?count=3&interval=10ms")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "data: ") {
+			events++
+		}
+	}
+	assert.Equal(t, 3, events)
+}
+
+func TestStreamingHandlersInvalidParams(t *testing.T) {
+	ts := newTestServer(t, Config{})
+
+	for _, path := range []string{
+		"/chunked?size=abc",
+		"/chunked?interval=-1s",
+		"/drip?bytes=-5",
+		"/delay/headers?delay=soon",
+		"/sse?count=x",
+	} {
+		status, _, _ := get(t, http.MethodGet, ts.URL+path)
+		assert.Equal(t, http.StatusBadRequest, status, path)
+	}
+}
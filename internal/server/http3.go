@@ -0,0 +1,40 @@
+package server
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// listenHTTP3 starts an HTTP/3 server on the UDP port matching addr and
+// returns it and its socket, which closing the server leaves open, along with
+// handler wrapped to advertise it with Alt-Svc
+func (s *Server) listenHTTP3(addr net.Addr, handler http.Handler, tlsCfg *tls.Config) (*http3.Server, net.PacketConn, http.Handler, error) {
+	pc, err := net.ListenPacket("udp", addr.String())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	h3 := &http3.Server{
+		Handler:   handler,
+		TLSConfig: http3.ConfigureTLSConfig(tlsCfg),
+	}
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		h3.Port = tcp.Port
+	}
+
+	s.Logger.Debug("Starting HTTP/3 server on %s", pc.LocalAddr())
+	go func() {
+		if err := h3.Serve(pc); err != nil && err != http.ErrServerClosed {
+			s.Logger.Error("HTTP/3 server stopped: %v", err)
+		}
+	}()
+
+	advertise := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h3.SetQUICHeaders(w.Header())
+		handler.ServeHTTP(w, r)
+	})
+	return h3, pc, advertise, nil
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/quic-go/quic-go/http3"
+	"github.com/rnemeth90/yahba/internal/config"
+	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/rnemeth90/yahba/internal/worker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeHTTP3(t *testing.T) {
+	addr := startServer(t, Config{HTTP3: true})
+
+	// HTTPS responses advertise the HTTP/3 endpoint
+	https := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	resp, err := https.Get("https://" + addr + "/test")
+	if !assert.NoError(t, err) {
+		return
+	}
+	resp.Body.Close()
+	assert.Contains(t, resp.Header.Get("Alt-Svc"), "h3=")
+
+	h3 := &http3.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	defer h3.Close()
+	resp, err = (&http.Client{Transport: h3}).Get("https://" + addr + "/test")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "HTTP/3.0", resp.Proto)
+}
+
+// TestHTTP3Report runs yahba over HTTP/3 and checks the report records the
+// protocol and the QUIC handshake
+func TestHTTP3Report(t *testing.T) {
+	addr := startServer(t, Config{HTTP3: true})
+
+	cfg := config.Config{
+		URL:      "https://" + addr + "/test",
+		Method:   "GET",
+		Requests: 5,
+		RPS:      5,
+		Timeout:  5,
+		Insecure: true,
+		HTTP3:    true,
+		Logger:   logger.New("error", "stdout", false),
+	}
+
+	results, err := worker.Start(context.Background(), cfg, worker.CreateJobs(cfg), worker.DefaultFactory)
+	assert.NoError(t, err)
+	r := worker.ProcessResults(cfg, results)
+
+	assert.Equal(t, 5, r.Successes)
+	if assert.NotNil(t, r.Protocols) {
+		assert.Equal(t, 5, r.Protocols.Counts["HTTP/3.0"])
+		assert.GreaterOrEqual(t, r.Protocols.Handshakes, 1)
+		assert.NotEmpty(t, r.Protocols.Handshake.Max)
+	}
+}
+
+func TestCloseBeforeServeHTTP3(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	s := &Server{Config: &Config{HTTP3: true}, Logger: logger.New("error", "stdout", false)}
+	s.Close()
+	assert.ErrorIs(t, s.Serve(l), http.ErrServerClosed)
+
+	// the HTTP/3 server was shut down along with its UDP socket
+	pc, err := net.ListenPacket("udp", l.Addr().String())
+	if assert.NoError(t, err) {
+		pc.Close()
+	}
+}
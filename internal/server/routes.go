@@ -0,0 +1,238 @@
+package server
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/logger"
+	"gopkg.in/yaml.v3"
+)
+
+// RouteFile is the YAML document loaded with --routes. For example:
+//
+//	routes:
+//	  - method: GET
+//	    path: /users/{id}
+//	    status: 200
+//	    headers:
+//	      Content-Type: application/json
+//	    body: '{"id": 1}'
+//	    delay: 20ms
+//	  - path: /flaky
+//	    responses:
+//	      - weight: 9
+//	        status: 200
+//	      - weight: 1
+//	        status: 503
+//	        body_file: errors/503.json
+type RouteFile struct {
+	Routes []Route `yaml:"routes"`
+}
+
+// Route maps a method and path pattern to a response, or to a weighted set
+// of alternative responses. Path patterns use net/http.ServeMux syntax.
+type Route struct {
+	Method    string `yaml:"method"`
+	Path      string `yaml:"path"`
+	Response  `yaml:",inline"`
+	Responses []Response `yaml:"responses"`
+}
+
+// Response is a canned response. BodyFile is relative to the route file.
+type Response struct {
+	Weight   int               `yaml:"weight"`
+	Status   int               `yaml:"status"`
+	Headers  map[string]string `yaml:"headers"`
+	Body     string            `yaml:"body"`
+	BodyFile string            `yaml:"body_file"`
+	Delay    time.Duration     `yaml:"delay"`
+}
+
+// routeTable serves the routes from a route file, falling back to the
+// built-in routes, and swaps in a new set whenever the file changes
+type routeTable struct {
+	path     string
+	fallback http.Handler
+	logger   *logger.Logger
+	mux      atomic.Pointer[http.ServeMux]
+	modTime  time.Time
+}
+
+func newRouteTable(path string, fallback http.Handler, l *logger.Logger) (*routeTable, error) {
+	rt := &routeTable{
+		path:     path,
+		fallback: fallback,
+		logger:   l,
+	}
+
+	rt.modTime = modTime(path)
+	if err := rt.load(); err != nil {
+		return nil, err
+	}
+	return rt, nil
+}
+
+func (rt *routeTable) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mux := rt.mux.Load()
+	if _, pattern := mux.Handler(r); pattern != "" {
+		mux.ServeHTTP(w, r)
+		return
+	}
+	rt.fallback.ServeHTTP(w, r)
+}
+
+// load parses the route file and replaces the active routes
+func (rt *routeTable) load() error {
+	data, err := os.ReadFile(rt.path)
+	if err != nil {
+		return err
+	}
+
+	var file RouteFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRouteFile, err)
+	}
+
+	mux, err := buildRouteMux(file, filepath.Dir(rt.path))
+	if err != nil {
+		return err
+	}
+
+	rt.mux.Store(mux)
+	rt.logger.Info("Loaded %d routes from %s", len(file.Routes), rt.path)
+	return nil
+}
+
+// watch polls the route file and reloads it when it changes. Invalid edits
+// are logged and the previous routes stay active.
+func (rt *routeTable) watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			current := modTime(rt.path)
+			if current.Equal(rt.modTime) {
+				continue
+			}
+			rt.modTime = current
+
+			if err := rt.load(); err != nil {
+				rt.logger.Error("Failed to reload routes from %s: %v", rt.path, err)
+			}
+		}
+	}
+}
+
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// buildRouteMux validates the routes and registers them on a new ServeMux
+func buildRouteMux(file RouteFile, dir string) (mux *http.ServeMux, err error) {
+	mux = http.NewServeMux()
+
+	// ServeMux panics on invalid or conflicting patterns
+	defer func() {
+		if r := recover(); r != nil {
+			mux, err = nil, fmt.Errorf("%w: %v", ErrInvalidRouteFile, r)
+		}
+	}()
+
+	for i, route := range file.Routes {
+		if !strings.HasPrefix(route.Path, "/") {
+			return nil, fmt.Errorf("%w: route %d: path must start with /", ErrInvalidRouteFile, i)
+		}
+
+		responses := route.Responses
+		if len(responses) == 0 {
+			responses = []Response{route.Response}
+		}
+
+		total := 0
+		for j := range responses {
+			if err := responses[j].prepare(dir, len(route.Responses) > 0); err != nil {
+				return nil, fmt.Errorf("%w: route %d: %v", ErrInvalidRouteFile, i, err)
+			}
+			total += responses[j].Weight
+		}
+
+		pattern := route.Path
+		if route.Method != "" {
+			pattern = strings.ToUpper(route.Method) + " " + route.Path
+		}
+		mux.Handle(pattern, routeHandler(responses, total))
+	}
+
+	return mux, nil
+}
+
+// prepare applies defaults and loads the body file
+func (resp *Response) prepare(dir string, weighted bool) error {
+	if resp.Status == 0 {
+		resp.Status = http.StatusOK
+	}
+	if resp.Status < 100 || resp.Status > 599 {
+		return fmt.Errorf("invalid status %d", resp.Status)
+	}
+
+	if weighted && resp.Weight <= 0 {
+		return fmt.Errorf("weight must be greater than 0")
+	}
+	if !weighted {
+		resp.Weight = 1
+	}
+
+	if resp.BodyFile != "" {
+		path := resp.BodyFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		resp.Body = string(data)
+	}
+
+	return nil
+}
+
+func routeHandler(responses []Response, totalWeight int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := responses[0]
+		if len(responses) > 1 {
+			n := rand.IntN(totalWeight)
+			for _, candidate := range responses {
+				if n < candidate.Weight {
+					resp = candidate
+					break
+				}
+				n -= candidate.Weight
+			}
+		}
+
+		if !sleep(r, resp.Delay) {
+			return
+		}
+
+		for k, v := range resp.Headers {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(resp.Status)
+		w.Write([]byte(resp.Body))
+	}
+}
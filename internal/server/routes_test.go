@@ -0,0 +1,134 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+const testRoutes = `
+routes:
+  - method: GET
+    path: /users/{id}
+    status: 201
+    headers:
+      Content-Type: application/json
+    body: '{"id": 1}'
+  - method: POST
+    path: /upload
+    body_file: body.txt
+  - path: /weighted
+    responses:
+      - weight: 1
+        status: 200
+      - weight: 1
+        status: 503
+`
+
+func writeRoutes(t *testing.T, dir, content string, mtime time.Time) string {
+	t.Helper()
+	path := filepath.Join(dir, "routes.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func get(t *testing.T, method, url string) (int, string, http.Header) {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body), resp.Header
+}
+
+func TestRoutes(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "body.txt"), []byte("from file"), 0644))
+	path := writeRoutes(t, dir, testRoutes, time.Now())
+
+	ts := newTestServer(t, Config{RoutesFile: path})
+
+	status, body, headers := get(t, http.MethodGet, ts.URL+"/users/42")
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, `{"id": 1}`, body)
+	assert.Equal(t, "application/json", headers.Get("Content-Type"))
+
+	status, body, _ = get(t, http.MethodPost, ts.URL+"/upload")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "from file", body)
+
+	// built-in routes are still served
+	status, _, _ = get(t, http.MethodGet, ts.URL+"/test")
+	assert.Equal(t, http.StatusOK, status)
+
+	seen := map[int]bool{}
+	for i := 0; i < 100; i++ {
+		status, _, _ = get(t, http.MethodGet, ts.URL+"/weighted")
+		seen[status] = true
+	}
+	assert.True(t, seen[http.StatusOK])
+	assert.True(t, seen[http.StatusServiceUnavailable])
+}
+
+func TestInvalidRoutes(t *testing.T) {
+	dir := t.TempDir()
+	l := logger.New("error", "stdout", false)
+
+	for _, content := range []string{
+		"routes: [",
+		"routes:\n  - path: nope",
+		"routes:\n  - path: /a\n    status: 999",
+		"routes:\n  - path: /a\n  - path: /a",
+		"routes:\n  - path: /a\n    responses:\n      - status: 200",
+		"routes:\n  - path: /a\n    body_file: missing.txt",
+	} {
+		path := writeRoutes(t, dir, content, time.Now())
+		_, err := newRouteTable(path, http.NotFoundHandler(), l)
+		assert.Error(t, err, content)
+	}
+}
+
+func TestRoutesHotReload(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	path := writeRoutes(t, dir, "routes:\n  - path: /reload\n    status: 200\n", start)
+
+	s := &Server{
+		Config: &Config{RoutesFile: path, ReloadInterval: 10 * time.Millisecond},
+		Logger: logger.New("error", "stdout", false),
+	}
+	defer s.Close()
+	handler, err := s.Handler()
+	assert.NoError(t, err)
+
+	check := func(req *http.Request) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/reload", nil)
+	assert.Equal(t, http.StatusOK, check(req))
+
+	writeRoutes(t, dir, "routes:\n  - path: /reload\n    status: 418\n", start.Add(time.Minute))
+	assert.Eventually(t, func() bool { return check(req) == http.StatusTeapot }, time.Second, 10*time.Millisecond)
+
+	// a broken edit keeps the previous routes
+	writeRoutes(t, dir, "routes: [", start.Add(2*time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, http.StatusTeapot, check(req))
+}
@@ -1,23 +1,159 @@
 package server
 
 import (
+	"net"
 	"net/http"
+	"sync"
+	"time"
 
+	"github.com/quic-go/quic-go/http3"
 	"github.com/rnemeth90/yahba/internal/logger"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 type Server struct {
 	Config *Config
 	Logger *logger.Logger
+
+	mu     sync.Mutex
+	closed bool
+	stop   chan struct{}
+	srv    *http.Server
+	h3     *http3.Server
+	h3conn net.PacketConn
+	stats  *stats
+}
+
+// Handler builds the server's routes, wrapped with the configured faults
+func (s *Server) Handler() (http.Handler, error) {
+	faults, err := newFaultInjector(s.Config.Faults, s.Logger)
+	if err != nil {
+		return nil, err
+	}
+
+	capacity, err := newCapacityLimiter(s.Config.Capacity, s.Logger)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	s.registerHandlers(mux)
+
+	var handler http.Handler = mux
+	if s.Config.RoutesFile != "" {
+		routes, err := newRouteTable(s.Config.RoutesFile, mux, s.Logger)
+		if err != nil {
+			return nil, err
+		}
+
+		interval := s.Config.ReloadInterval
+		if interval <= 0 {
+			interval = time.Second
+		}
+		s.mu.Lock()
+		if s.stop == nil {
+			s.stop = make(chan struct{})
+		}
+		go routes.watch(interval, s.stop)
+		s.mu.Unlock()
+
+		handler = routes
+	}
+
+	s.mu.Lock()
+	s.stats = newStats()
+	handler = s.stats.wrap(capacity.wrap(faults.wrap(handler)))
+	s.mu.Unlock()
+
+	if s.Config.H2C {
+		handler = h2c.NewHandler(handler, &http2.Server{})
+	}
+
+	return handler, nil
+}
+
+// Close stops the server and watching the route file. A Serve call that is
+// still starting up stops as soon as it is ready.
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	s.shutdown()
+}
+
+// shutdown stops whatever has been started so far. The caller holds s.mu.
+func (s *Server) shutdown() {
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+	if s.srv != nil {
+		s.srv.Close()
+	}
+	if s.h3 != nil {
+		s.h3.Close()
+		s.h3conn.Close()
+	}
 }
 
 func (s *Server) Run() error {
-	http.HandleFunc("/test", testHandler)
+	if s.Config.Port == "" {
+		return ErrInvalidPort
+	}
+
+	l, err := net.Listen("tcp", s.Config.Port)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l until the server is closed
+func (s *Server) Serve(l net.Listener) error {
+	usesTLS := s.Config.TLS || s.Config.HTTP3 || s.Config.CertFile != "" || s.Config.ClientCAFile != "" || s.Config.RequireClientCert
+	if s.Config.H2C && usesTLS {
+		return ErrConflictingTLSOptions
+	}
+
+	handler, err := s.Handler()
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{Handler: handler, ConnState: s.stats.connState}
+	if usesTLS {
+		if srv.TLSConfig, err = s.tlsConfig(); err != nil {
+			return err
+		}
+	}
+
+	var h3 *http3.Server
+	var h3conn net.PacketConn
+	if s.Config.HTTP3 {
+		if h3, h3conn, srv.Handler, err = s.listenHTTP3(l.Addr(), handler, srv.TLSConfig); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	s.srv = srv
+	s.h3 = h3
+	s.h3conn = h3conn
+	if s.closed {
+		s.shutdown()
+		s.mu.Unlock()
+		return http.ErrServerClosed
+	}
+	s.mu.Unlock()
 
-	if s.Config.Port != "" {
-		s.Logger.Debug("Starting server on port %s", s.Config.Port)
-		return http.ListenAndServe(s.Config.Port, nil)
+	if usesTLS {
+		s.Logger.Debug("Starting HTTPS server on %s", l.Addr())
+		return srv.ServeTLS(l, "", "")
 	}
 
-	return ErrInvalidPort
+	s.Logger.Debug("Starting server on %s", l.Addr())
+	return srv.Serve(l)
 }
@@ -1 +1,42 @@
 package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T, cfg Config) *httptest.Server {
+	t.Helper()
+	s := &Server{
+		Config: &cfg,
+		Logger: logger.New("error", "stdout", false),
+	}
+	handler, err := s.Handler()
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+	ts := httptest.NewServer(handler)
+	t.Cleanup(func() {
+		ts.Close()
+		s.Close()
+	})
+	return ts
+}
+
+func TestRunRequiresPort(t *testing.T) {
+	s := &Server{Config: &Config{}, Logger: logger.New("error", "stdout", false)}
+	assert.ErrorIs(t, s.Run(), ErrInvalidPort)
+}
+
+func TestTestHandler(t *testing.T) {
+	ts := newTestServer(t, Config{})
+
+	resp, err := http.Get(ts.URL + "/test")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
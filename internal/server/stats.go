@@ -0,0 +1,239 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/report"
+)
+
+const (
+	// StatsPath serves the request counters as JSON
+	StatsPath = "/_stats"
+	// StatsResetPath clears the request counters
+	StatsResetPath = "/_stats/reset"
+)
+
+// stats counts everything the server receives so a load test can be cross-checked
+type stats struct {
+	mu   sync.Mutex
+	data report.ServerStats
+}
+
+func newStats() *stats {
+	s := &stats{}
+	s.reset()
+	return s
+}
+
+func (s *stats) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = report.ServerStats{
+		Since:     time.Now(),
+		Methods:   map[string]int{},
+		Paths:     map[string]int{},
+		Statuses:  map[string]int{},
+		Protocols: map[string]int{},
+	}
+}
+
+func (s *stats) snapshot() report.ServerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap := s.data
+	snap.Methods = copyCounts(s.data.Methods)
+	snap.Paths = copyCounts(s.data.Paths)
+	snap.Statuses = copyCounts(s.data.Statuses)
+	snap.Protocols = copyCounts(s.data.Protocols)
+	return snap
+}
+
+func (s *stats) connectionAccepted() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Connections++
+}
+
+func (s *stats) record(r *http.Request, status int, bytesReceived int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Requests++
+	s.data.Methods[r.Method]++
+	s.data.Paths[r.URL.Path]++
+	s.data.Protocols[r.Proto]++
+	s.data.BytesReceived += bytesReceived
+
+	// requests whose connection was reset or that hung never get a status
+	if status != 0 {
+		s.data.Statuses[strconv.Itoa(status)]++
+	} else {
+		s.data.Statuses[report.NoResponseKey]++
+	}
+}
+
+// connState counts accepted connections for http.Server.ConnState
+func (s *stats) connState(_ net.Conn, state http.ConnState) {
+	if state == http.StateNew {
+		s.connectionAccepted()
+	}
+}
+
+// wrap counts every request except those to the stats endpoints themselves
+func (s *stats) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case StatsPath:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(s.snapshot())
+			return
+		case StatsResetPath:
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			s.reset()
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if r.Body == nil {
+			r.Body = http.NoBody
+		}
+		body := &countingReader{ReadCloser: r.Body}
+		r.Body = body
+		rec := &statusRecorder{ResponseWriter: w}
+
+		defer func() {
+			// drain what the handler didn't read so the body is counted in full
+			io.Copy(io.Discard, body)
+			s.record(r, rec.status, body.n)
+		}()
+
+		next.ServeHTTP(rec, r)
+	})
+}
+
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// statusRecorder captures the status code while still supporting the
+// flushing and hijacking the streaming and fault handlers rely on
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Flush() {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return hj.Hijack()
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func copyCounts(m map[string]int) map[string]int {
+	c := make(map[string]int, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// FetchStats retrieves the counters from the test server that serves target
+func FetchStats(client *http.Client, target string) (report.ServerStats, error) {
+	var stats report.ServerStats
+
+	u, err := statsURL(target, StatsPath)
+	if err != nil {
+		return stats, err
+	}
+
+	resp, err := client.Get(u)
+	if err != nil {
+		return stats, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return stats, fmt.Errorf("%w: unexpected status %d from %s", ErrStatsUnavailable, resp.StatusCode, u)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return stats, fmt.Errorf("%w: %v", ErrStatsUnavailable, err)
+	}
+	return stats, nil
+}
+
+// ResetStats clears the counters on the test server that serves target
+func ResetStats(client *http.Client, target string) error {
+	u, err := statsURL(target, StatsResetPath)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(u, "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("%w: unexpected status %d from %s", ErrStatsUnavailable, resp.StatusCode, u)
+	}
+	return nil
+}
+
+// statsURL swaps the path of target for a stats endpoint
+func statsURL(target, path string) (string, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: path}).String(), nil
+}
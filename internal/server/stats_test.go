@@ -0,0 +1,63 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rnemeth90/yahba/internal/report"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	addr := startServer(t, Config{})
+	base := "http://" + addr
+
+	resp, err := http.Get(base + "/test")
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	resp, err = http.Post(base+"/test", "text/plain", strings.NewReader("hello"))
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	resp, err = http.Get(base + "/missing")
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	stats, err := FetchStats(http.DefaultClient, base+"/anything")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, stats.Requests)
+	assert.Equal(t, map[string]int{"GET": 2, "POST": 1}, stats.Methods)
+	assert.Equal(t, map[string]int{"/test": 2, "/missing": 1}, stats.Paths)
+	assert.Equal(t, map[string]int{"200": 2, "404": 1}, stats.Statuses)
+	assert.Equal(t, map[string]int{"HTTP/1.1": 3}, stats.Protocols)
+	assert.Equal(t, int64(5), stats.BytesReceived)
+	assert.GreaterOrEqual(t, stats.Connections, 1)
+
+	assert.NoError(t, ResetStats(http.DefaultClient, base))
+	stats, err = FetchStats(http.DefaultClient, base)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, stats.Requests)
+	assert.Empty(t, stats.Statuses)
+}
+
+func TestStatsCountsFaults(t *testing.T) {
+	addr := startServer(t, Config{Faults: FaultConfig{ResetRate: 100}})
+
+	_, err := http.Get("http://" + addr + "/test")
+	assert.Error(t, err)
+
+	stats, err := FetchStats(http.DefaultClient, "http://"+addr)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.Statuses[report.NoResponseKey])
+}
+
+func TestFetchStatsUnavailable(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	_, err := FetchStats(http.DefaultClient, ts.URL)
+	assert.ErrorIs(t, err, ErrStatsUnavailable)
+}
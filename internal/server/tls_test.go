@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+)
+
+// startServer runs the server on a random local port and returns its address
+func startServer(t *testing.T, cfg Config) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{Config: &cfg, Logger: logger.New("error", "stdout", false)}
+	go s.Serve(l)
+	t.Cleanup(s.Close)
+
+	return l.Addr().String()
+}
+
+func TestServeTLSWithHTTP2(t *testing.T) {
+	addr := startServer(t, Config{TLS: true})
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	}}
+
+	resp, err := client.Get("https://" + addr + "/test")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "HTTP/2.0", resp.Proto)
+}
+
+func TestServeH2C(t *testing.T) {
+	addr := startServer(t, Config{H2C: true})
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}}
+
+	resp, err := client.Get("http://" + addr + "/test")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "HTTP/2.0", resp.Proto)
+
+	// plain HTTP/1.1 clients still work
+	resp, err = http.Get("http://" + addr + "/test")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "HTTP/1.1", resp.Proto)
+}
+
+func TestServeRequiresClientCert(t *testing.T) {
+	cert, err := generateSelfSignedCert()
+	assert.NoError(t, err)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	assert.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0644))
+
+	addr := startServer(t, Config{TLS: true, ClientCAFile: caFile})
+
+	anonymous := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	_, err = anonymous.Get("https://" + addr + "/test")
+	assert.Error(t, err)
+
+	authenticated := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true, Certificates: []tls.Certificate{cert}},
+	}}
+	resp, err := authenticated.Get("https://" + addr + "/test")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestServeTLSValidation(t *testing.T) {
+	l := logger.New("error", "stdout", false)
+
+	s := &Server{Config: &Config{TLS: true, H2C: true}, Logger: l}
+	assert.ErrorIs(t, s.Serve(nil), ErrConflictingTLSOptions)
+
+	s = &Server{Config: &Config{CertFile: "cert.pem"}, Logger: l}
+	_, err := s.tlsConfig()
+	assert.ErrorIs(t, err, ErrInvalidCertificate)
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{
+	// the test server accepts connections from any origin
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// wsEchoHandler echoes every WebSocket message back to the client, after
+// delay. With close_after it closes the connection normally once that many
+// messages have been echoed.
+//
+//	/ws?delay=10ms&close_after=100
+func (s *Server) wsEchoHandler(w http.ResponseWriter, r *http.Request) {
+	delay, err1 := durationParam(r, "delay", 0)
+	closeAfter, err2 := intParam(r, "close_after", 0)
+	if err := firstError(err1, err2); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already replied with an error
+		s.Logger.Debug("WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	for echoed := 0; closeAfter == 0 || echoed < closeAfter; echoed++ {
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+		if err := conn.WriteMessage(messageType, message); err != nil {
+			return
+		}
+	}
+
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, "close_after reached"),
+		time.Now().Add(time.Second))
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebSocketEcho(t *testing.T) {
+	ts := newTestServer(t, Config{})
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws?close_after=2"
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	for _, message := range []string{"hello", "world"} {
+		assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(message)))
+		_, reply, err := conn.ReadMessage()
+		assert.NoError(t, err)
+		assert.Equal(t, message, string(reply))
+	}
+
+	// the server closes the connection after two messages
+	_, _, err = conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if assert.True(t, errors.As(err, &closeErr)) {
+		assert.Equal(t, websocket.CloseNormalClosure, closeErr.Code)
+	}
+}
+
+func TestWebSocketEchoRejectsBadParams(t *testing.T) {
+	ts := newTestServer(t, Config{})
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws?delay=soon"
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	assert.ErrorIs(t, err, websocket.ErrBadHandshake)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, 400, resp.StatusCode)
+	}
+}
@@ -0,0 +1,95 @@
+package signing
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rnemeth90/yahba/internal/config"
+)
+
+type awsCredentials struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// loadAWSCredentials reads credentials from the standard AWS environment
+// variables, falling back to a profile in the shared credentials file
+func loadAWSCredentials(cfg config.Config) (awsCredentials, error) {
+	creds := awsCredentials{
+		accessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.accessKeyID != "" && creds.secretAccessKey != "" {
+		return creds, nil
+	}
+
+	path := cfg.AWSCredentialsFile
+	if path == "" {
+		path = os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	}
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return creds, ErrMissingCredentials
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+
+	profile := cfg.AWSProfile
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+
+	return readCredentialsFile(path, profile)
+}
+
+// readCredentialsFile reads a profile from an AWS shared credentials file
+func readCredentialsFile(path, profile string) (awsCredentials, error) {
+	var creds awsCredentials
+	f, err := os.Open(path)
+	if err != nil {
+		return creds, ErrMissingCredentials
+	}
+	defer f.Close()
+
+	var section string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if section != profile {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "aws_access_key_id":
+			creds.accessKeyID = strings.TrimSpace(value)
+		case "aws_secret_access_key":
+			creds.secretAccessKey = strings.TrimSpace(value)
+		case "aws_session_token":
+			creds.sessionToken = strings.TrimSpace(value)
+		}
+	}
+
+	if creds.accessKeyID == "" || creds.secretAccessKey == "" {
+		return creds, ErrMissingCredentials
+	}
+	return creds, nil
+}
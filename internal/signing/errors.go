@@ -0,0 +1,8 @@
+package signing
+
+import "errors"
+
+var (
+	ErrMissingSecret      = errors.New("signing secret is empty")
+	ErrMissingCredentials = errors.New("AWS credentials not found in the environment or the shared credentials file")
+)
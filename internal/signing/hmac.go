@@ -0,0 +1,69 @@
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/config"
+)
+
+// Default headers for HMAC signatures
+const (
+	DefaultSignatureHeader = "X-Signature"
+	DefaultTimestampHeader = "X-Timestamp"
+)
+
+// hmacSigner signs the method, request URI, Unix timestamp and the hex
+// SHA-256 of the body, one per line, and sends the hex signature and the
+// timestamp in headers
+type hmacSigner struct {
+	secret          []byte
+	newHash         func() hash.Hash
+	signatureHeader string
+	timestampHeader string
+}
+
+func newHMACSigner(cfg config.Config, secret []byte) *hmacSigner {
+	s := &hmacSigner{
+		secret:          secret,
+		newHash:         sha256.New,
+		signatureHeader: cfg.SignHeader,
+		timestampHeader: cfg.SignTimestampHeader,
+	}
+	if cfg.Sign == HMACSHA512 {
+		s.newHash = sha512.New
+	}
+	if s.signatureHeader == "" {
+		s.signatureHeader = DefaultSignatureHeader
+	}
+	if s.timestampHeader == "" {
+		s.timestampHeader = DefaultTimestampHeader
+	}
+	return s
+}
+
+func (s *hmacSigner) Sign(req *http.Request, body []byte, now time.Time) error {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	bodyHash := sha256.Sum256(body)
+
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.URL.RequestURI(),
+		timestamp,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+
+	mac := hmac.New(s.newHash, s.secret)
+	mac.Write([]byte(stringToSign))
+
+	req.Header.Set(s.timestampHeader, timestamp)
+	req.Header.Set(s.signatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	return nil
+}
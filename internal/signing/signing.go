@@ -0,0 +1,63 @@
+package signing
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/config"
+)
+
+// Signing algorithms
+const (
+	HMACSHA256 = "hmac-sha256"
+	HMACSHA512 = "hmac-sha512"
+	AWSSigV4   = "aws-sigv4"
+)
+
+// Signer adds a signature to a request. body is the request body, which is
+// passed separately so signing never consumes req.Body.
+type Signer interface {
+	Sign(req *http.Request, body []byte, now time.Time) error
+}
+
+// New returns the signer for the configured algorithm, or nil when requests
+// are not signed. Credentials are read once, here.
+func New(cfg config.Config) (Signer, error) {
+	switch cfg.Sign {
+	case HMACSHA256, HMACSHA512:
+		secret, err := loadSecret(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return newHMACSigner(cfg, secret), nil
+	case AWSSigV4:
+		creds, err := loadAWSCredentials(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &sigV4Signer{creds: creds, region: cfg.AWSRegion, service: cfg.AWSService}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// loadSecret reads the HMAC secret from the environment variable or file
+func loadSecret(cfg config.Config) ([]byte, error) {
+	var secret string
+	if cfg.SignSecretEnv != "" {
+		secret = os.Getenv(cfg.SignSecretEnv)
+	} else {
+		b, err := os.ReadFile(cfg.SignSecretFile)
+		if err != nil {
+			return nil, err
+		}
+		secret = strings.TrimRight(string(b), "\r\n")
+	}
+
+	if secret == "" {
+		return nil, ErrMissingSecret
+	}
+	return []byte(secret), nil
+}
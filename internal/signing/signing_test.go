@@ -0,0 +1,77 @@
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	s, err := New(config.Config{})
+	assert.NoError(t, err)
+	assert.Nil(t, s)
+
+	t.Setenv("YAHBA_SECRET", "")
+	_, err = New(config.Config{Sign: HMACSHA256, SignSecretEnv: "YAHBA_SECRET"})
+	assert.ErrorIs(t, err, ErrMissingSecret)
+
+	_, err = New(config.Config{Sign: HMACSHA256, SignSecretFile: filepath.Join(t.TempDir(), "missing")})
+	assert.Error(t, err)
+}
+
+func TestHMACSignature(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := `{"hello":"world"}`
+	bodyHash := sha256.Sum256([]byte(body))
+	stringToSign := "POST\n/orders?id=7\n1700000000\n" + hex.EncodeToString(bodyHash[:])
+
+	for _, tc := range []struct {
+		algorithm string
+		newHash   func() hash.Hash
+	}{
+		{HMACSHA256, sha256.New},
+		{HMACSHA512, sha512.New},
+	} {
+		t.Run(tc.algorithm, func(t *testing.T) {
+			t.Setenv("YAHBA_SECRET", "s3cret")
+			s, err := New(config.Config{Sign: tc.algorithm, SignSecretEnv: "YAHBA_SECRET"})
+			assert.NoError(t, err)
+
+			req, _ := http.NewRequest("POST", "http://localhost/orders?id=7", strings.NewReader(body))
+			assert.NoError(t, s.Sign(req, []byte(body), now))
+
+			assert.Equal(t, "1700000000", req.Header.Get(DefaultTimestampHeader))
+			mac := hmac.New(tc.newHash, []byte("s3cret"))
+			mac.Write([]byte(stringToSign))
+			assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), req.Header.Get(DefaultSignatureHeader))
+		})
+	}
+}
+
+func TestHMACSecretFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	assert.NoError(t, os.WriteFile(path, []byte("s3cret\n"), 0600))
+
+	s, err := New(config.Config{Sign: HMACSHA256, SignSecretFile: path, SignHeader: "X-Sig", SignTimestampHeader: "X-Ts"})
+	assert.NoError(t, err)
+
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	assert.NoError(t, s.Sign(req, nil, time.Unix(1700000000, 0)))
+
+	bodyHash := sha256.Sum256(nil)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte("GET\n/\n1700000000\n" + hex.EncodeToString(bodyHash[:])))
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), req.Header.Get("X-Sig"))
+	assert.Equal(t, "1700000000", req.Header.Get("X-Ts"))
+}
@@ -0,0 +1,70 @@
+package signing
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSigV4 checks the get-vanilla case from the AWS SigV4 test suite
+func TestSigV4(t *testing.T) {
+	s := &sigV4Signer{
+		creds:   awsCredentials{accessKeyID: "AKIDEXAMPLE", secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"},
+		region:  "us-east-1",
+		service: "service",
+	}
+
+	req, _ := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	assert.NoError(t, s.Sign(req, nil, time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)))
+
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, "+
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"))
+}
+
+func TestCanonicalQuery(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://example.amazonaws.com/?b=2&a=x y&a=1", nil)
+	assert.Equal(t, "a=1&a=x%20y&b=2", canonicalQuery(req.URL))
+}
+
+func TestAWSCredentials(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_PROFILE", "")
+
+	path := filepath.Join(t.TempDir(), "credentials")
+	assert.NoError(t, os.WriteFile(path, []byte(`[default]
+aws_access_key_id = AKIDDEFAULT
+aws_secret_access_key = default-secret
+
+# a temporary session
+[staging]
+aws_access_key_id = AKIDSTAGING
+aws_secret_access_key = staging-secret
+aws_session_token = token
+`), 0600))
+
+	creds, err := loadAWSCredentials(config.Config{AWSCredentialsFile: path})
+	assert.NoError(t, err)
+	assert.Equal(t, "AKIDDEFAULT", creds.accessKeyID)
+
+	creds, err = loadAWSCredentials(config.Config{AWSCredentialsFile: path, AWSProfile: "staging"})
+	assert.NoError(t, err)
+	assert.Equal(t, awsCredentials{"AKIDSTAGING", "staging-secret", "token"}, creds)
+
+	_, err = loadAWSCredentials(config.Config{AWSCredentialsFile: path, AWSProfile: "prod"})
+	assert.ErrorIs(t, err, ErrMissingCredentials)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDENV")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+	creds, err = loadAWSCredentials(config.Config{AWSCredentialsFile: path})
+	assert.NoError(t, err)
+	assert.Equal(t, "AKIDENV", creds.accessKeyID)
+}
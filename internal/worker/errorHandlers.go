@@ -1,24 +1,35 @@
 package worker
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/rnemeth90/yahba/internal/auth"
 	"github.com/rnemeth90/yahba/internal/report"
 )
 
-func (w *Worker) handleClientError(job Job, result report.Result, resp *http.Response, err error, start time.Time, end time.Time) {
+func (w *Worker) handleClientError(job Job, result report.Result, resp *http.Response, err error, start time.Time, end time.Time) report.Result {
 	if urlErr, ok := err.(*url.Error); ok && urlErr.Timeout() {
 		w.Config.Logger.Warn("worker %d: Request to %s timed out", w.ID, job.Host)
+		// no response arrived, so the result stays in the no response bucket
+		// and can be told apart from a 408 sent by the server
 		result.Timeout = true
-		result.ResultCode = http.StatusRequestTimeout
-		// urlErr.Unwrap()
 	} else {
 		w.Config.Logger.Error("worker %d: Request to %s failed: %v", w.ID, job.Host, err)
 	}
 
 	result.Error = err
+	result.ErrorClass = ClassifyError(err)
 	result.EndTime = end
 	result.ElapsedTime = result.EndTime.Sub(start)
 
@@ -28,14 +39,91 @@ func (w *Worker) handleClientError(job Job, result report.Result, resp *http.Res
 		result.TargetURL = resp.Request.URL.RawPath
 	}
 
-	w.Results <- result
+	return result
 }
 
-func (w *Worker) handleRequestError(job Job, err error) {
-	w.Results <- report.Result{
-		WorkerID:  w.ID,
-		Method:    job.Method,
-		TargetURL: job.Host,
-		Error:     err,
+func (w *Worker) handleRequestError(job Job, err error) report.Result {
+	return report.Result{
+		WorkerID:   w.ID,
+		Method:     job.Method,
+		TargetURL:  job.Host,
+		Error:      err,
+		ErrorClass: report.ErrorClassRequestCreation,
+		EndTime:    time.Now(),
 	}
 }
+
+// ClassifyError maps an error returned by the HTTP client to one of the
+// report.ErrorClass* values. Order matters: a proxy dial that is refused is a
+// proxy error, and a timeout during a TLS handshake is a timeout.
+func ClassifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return report.ErrorClassContextCanceled
+	}
+
+	// a token request that timed out is an auth failure, not a slow target
+	if errors.Is(err, auth.ErrTokenRequest) || errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrInvalidChallenge) {
+		return report.ErrorClassAuth
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "proxyconnect" {
+		return report.ErrorClassProxy
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) ||
+		(errors.As(err, &netErr) && netErr.Timeout()) {
+		return report.ErrorClassTimeout
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return report.ErrorClassDNS
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return report.ErrorClassConnRefused
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return report.ErrorClassConnReset
+	}
+
+	if isTLSError(err) {
+		return report.ErrorClassTLSHandshake
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return report.ErrorClassEOF
+	}
+
+	// some proxy failures (e.g. a non-200 CONNECT response) are plain strings
+	if strings.Contains(err.Error(), "proxyconnect") {
+		return report.ErrorClassProxy
+	}
+
+	return report.ErrorClassOther
+}
+
+// isTLSError reports whether err was caused by a failed TLS handshake
+func isTLSError(err error) bool {
+	var recordErr tls.RecordHeaderError
+	var certErr *tls.CertificateVerificationError
+	var alertErr tls.AlertError
+	var unknownAuthErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+
+	switch {
+	case errors.As(err, &recordErr), errors.As(err, &certErr), errors.As(err, &alertErr),
+		errors.As(err, &unknownAuthErr), errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return true
+	}
+
+	return strings.Contains(err.Error(), "tls: ")
+}
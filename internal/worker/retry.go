@@ -0,0 +1,114 @@
+package worker
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/config"
+	"github.com/rnemeth90/yahba/internal/report"
+)
+
+// retryPolicy decides whether a failed attempt is retried and how long to
+// wait before the next one
+type retryPolicy struct {
+	maxAttempts  int
+	statusCodes  map[int]bool
+	errorClasses map[string]bool
+	backoff      time.Duration
+	maxBackoff   time.Duration
+}
+
+// newRetryPolicy builds the policy from a validated config
+func newRetryPolicy(cfg config.Config) retryPolicy {
+	p := retryPolicy{
+		maxAttempts:  max(1, cfg.MaxAttempts),
+		statusCodes:  make(map[int]bool),
+		errorClasses: make(map[string]bool),
+		backoff:      cfg.RetryBackoff,
+		maxBackoff:   cfg.RetryMaxBackoff,
+	}
+
+	codes, _ := config.ParseStatusCodes(cfg.RetryStatusCodes)
+	for _, code := range codes {
+		p.statusCodes[code] = true
+	}
+
+	for _, class := range strings.Split(cfg.RetryErrors, ",") {
+		if class = strings.TrimSpace(class); class != "" {
+			p.errorClasses[class] = true
+		}
+	}
+
+	return p
+}
+
+// shouldRetry reports whether the result of the given attempt should be
+// retried, and why: the status code or the error class
+func (p retryPolicy) shouldRetry(result report.Result, attempt int) (bool, string) {
+	if attempt >= p.maxAttempts {
+		return false, ""
+	}
+
+	if result.ErrorClass != "" {
+		if result.Timeout && p.errorClasses[report.ErrorClassTimeout] {
+			return true, report.ErrorClassTimeout
+		}
+		return p.errorClasses[result.ErrorClass], result.ErrorClass
+	}
+
+	if p.statusCodes[result.ResultCode] {
+		return true, strconv.Itoa(result.ResultCode)
+	}
+
+	return false, ""
+}
+
+// delay returns the wait before the attempt after the given one. A
+// Retry-After from the server wins over the exponential backoff, which uses
+// equal jitter: half the backoff plus a random amount up to the other half.
+// Both are capped at the maximum backoff.
+func (p retryPolicy) delay(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		if p.maxBackoff > 0 {
+			return min(retryAfter, p.maxBackoff)
+		}
+		return retryAfter
+	}
+
+	if p.backoff <= 0 {
+		return 0
+	}
+
+	backoff := p.backoff << (attempt - 1)
+	if backoff <= 0 || (p.maxBackoff > 0 && backoff > p.maxBackoff) {
+		backoff = p.maxBackoff
+	}
+
+	half := backoff / 2
+	return half + rand.N(backoff-half+1)
+}
+
+// parseRetryAfter parses a Retry-After header, which is either a number of
+// seconds or an HTTP date
+func parseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(header); err == nil {
+		return max(0, at.Sub(now)), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,110 @@
+package worker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/config"
+	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/rnemeth90/yahba/internal/report"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldRetry(t *testing.T) {
+	p := newRetryPolicy(config.Config{
+		MaxAttempts:      3,
+		RetryStatusCodes: "429,503",
+		RetryErrors:      "connection_reset,timeout",
+	})
+
+	tests := []struct {
+		name    string
+		result  report.Result
+		attempt int
+		retry   bool
+		reason  string
+	}{
+		{"retryable status", report.Result{ResultCode: 503}, 1, true, "503"},
+		{"non-retryable status", report.Result{ResultCode: 500}, 1, false, ""},
+		{"success", report.Result{ResultCode: 200}, 1, false, ""},
+		{"retryable error", report.Result{ErrorClass: report.ErrorClassConnReset}, 2, true, report.ErrorClassConnReset},
+		{"non-retryable error", report.Result{ErrorClass: report.ErrorClassDNS}, 1, false, report.ErrorClassDNS},
+		{"timeout", report.Result{ErrorClass: report.ErrorClassOther, Timeout: true}, 1, true, report.ErrorClassTimeout},
+		{"attempts exhausted", report.Result{ResultCode: 503}, 3, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retry, reason := p.shouldRetry(tt.result, tt.attempt)
+			assert.Equal(t, tt.retry, retry)
+			if tt.retry {
+				assert.Equal(t, tt.reason, reason)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	p := newRetryPolicy(config.Config{RetryBackoff: 100 * time.Millisecond, RetryMaxBackoff: time.Second})
+
+	for attempt, backoff := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second} {
+		d := p.delay(attempt, 0)
+		assert.GreaterOrEqual(t, d, backoff/2, "attempt %d", attempt)
+		assert.LessOrEqual(t, d, backoff, "attempt %d", attempt)
+	}
+
+	// Retry-After wins over the backoff but is still capped
+	assert.Equal(t, 500*time.Millisecond, p.delay(1, 500*time.Millisecond))
+	assert.Equal(t, time.Second, p.delay(1, time.Minute))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	d, ok := parseRetryAfter("3", now)
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, d)
+
+	d, ok = parseRetryAfter(now.Add(10*time.Second).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Equal(t, 10*time.Second, d)
+
+	for _, h := range []string{"", "-1", "soon"} {
+		_, ok := parseRetryAfter(h, now)
+		assert.False(t, ok, h)
+	}
+}
+
+func TestProcessJobRetries(t *testing.T) {
+	var hits atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := config.Config{
+		Logger:           logger.New("error", "stdout", false),
+		MaxAttempts:      5,
+		RetryStatusCodes: "503",
+		RetryBackoff:     time.Millisecond,
+	}
+	results := make(chan report.Result, 1)
+	worker := NewWorker(1, nil, results, server.Client(), cfg)
+
+	worker.processJob(context.Background(), Job{Host: server.URL, Method: "GET"})
+	result := <-results
+
+	assert.Equal(t, 3, result.Attempts)
+	assert.Equal(t, http.StatusOK, result.ResultCode)
+	assert.Equal(t, http.StatusServiceUnavailable, result.FirstResultCode)
+	assert.Equal(t, []string{"503", "503"}, result.RetryReasons)
+}
@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/report"
+)
+
+// requestTrace records connection level timings for a single request. The
+// hooks may fire from transport goroutines, so access is guarded.
+type requestTrace struct {
+	mu             sync.Mutex
+	handshakeStart time.Time
+	handshake      time.Duration
+	gotConn        bool
+	reused         bool
+	localAddr      net.Addr
+	remoteAddr     net.Addr
+}
+
+// withTrace attaches the trace hooks to ctx
+func (t *requestTrace) withTrace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.handshakeStart = time.Now()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.gotConn = true
+			t.reused = info.Reused
+			if info.Conn != nil {
+				t.localAddr = info.Conn.LocalAddr()
+				t.remoteAddr = info.Conn.RemoteAddr()
+			}
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if !t.handshakeStart.IsZero() {
+				t.handshake = time.Since(t.handshakeStart)
+			}
+		},
+	})
+}
+
+// handshakeTime returns how long the handshake took, or zero when the request
+// reused an existing connection
+func (t *requestTrace) handshakeTime() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.handshake
+}
+
+// connection returns whether the request reused a pooled connection, or an
+// empty string when the transport did not report it
+func (t *requestTrace) connection() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch {
+	case !t.gotConn:
+		return ""
+	case t.reused:
+		return report.ConnectionReused
+	default:
+		return report.ConnectionFresh
+	}
+}
+
+// sourceIP returns the local IP of the connection the request used
+func (t *requestTrace) sourceIP() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return addrIP(t.localAddr)
+}
+
+// remoteIP returns the IP of the peer of the connection the request used
+func (t *requestTrace) remoteIP() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return addrIP(t.remoteAddr)
+}
+
+// addrIP returns the IP of a TCP or UDP address
+func addrIP(addr net.Addr) string {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP.String()
+	case *net.UDPAddr:
+		return a.IP.String()
+	}
+	return ""
+}
@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rnemeth90/yahba/internal/config"
+	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnectionMetrics(t *testing.T) {
+	ts := mockServer()
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		name    string
+		newConn bool
+		opened  int
+		fresh   int
+		reused  int
+		perConn float64
+	}{
+		{"pooled", false, 1, 1, 4, 5},
+		{"new connection per request", true, 5, 5, 0, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.Config{
+				URL:               ts.URL,
+				Method:            "GET",
+				Requests:          5,
+				RPS:               20,
+				Timeout:           5,
+				NewConnPerRequest: tc.newConn,
+				Logger:            logger.New("error", "stdout", false),
+			}
+
+			results, err := Start(context.Background(), cfg, CreateJobs(cfg), DefaultFactory)
+			assert.NoError(t, err)
+			r := ProcessResults(cfg, results)
+
+			if assert.NotNil(t, r.Connections) {
+				assert.Equal(t, tc.opened, r.Connections.Opened)
+				assert.Equal(t, tc.fresh, r.Connections.FreshRequests)
+				assert.Equal(t, tc.reused, r.Connections.ReusedRequests)
+				assert.Equal(t, tc.perConn, r.Connections.RequestsPerConnection)
+			}
+		})
+	}
+}
+
+func TestSourceAddrStats(t *testing.T) {
+	ts := mockServer()
+	defer ts.Close()
+
+	cfg := config.Config{
+		URL:               ts.URL,
+		Method:            "GET",
+		Requests:          4,
+		RPS:               20,
+		Timeout:           5,
+		NewConnPerRequest: true,
+		SourceAddrs:       "127.0.0.2,127.0.0.3",
+		Logger:            logger.New("error", "stdout", false),
+	}
+
+	results, err := Start(context.Background(), cfg, CreateJobs(cfg), DefaultFactory)
+	assert.NoError(t, err)
+	r := ProcessResults(cfg, results)
+
+	assert.Len(t, r.Sources, 2)
+	assert.Equal(t, 2, r.Sources["127.0.0.2"].Requests)
+	assert.Equal(t, 2, r.Sources["127.0.0.3"].Successes)
+}
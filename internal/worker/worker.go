@@ -9,9 +9,11 @@ import (
 	"sync"
 	"time"
 
+	"github.com/rnemeth90/yahba/internal/auth"
 	"github.com/rnemeth90/yahba/internal/client"
 	"github.com/rnemeth90/yahba/internal/config"
 	"github.com/rnemeth90/yahba/internal/report"
+	"github.com/rnemeth90/yahba/internal/signing"
 	"github.com/rnemeth90/yahba/internal/util"
 )
 
@@ -21,6 +23,10 @@ type Worker struct {
 	Results chan<- report.Result
 	Client  *http.Client
 	Config  config.Config
+
+	retry  retryPolicy
+	signer signing.Signer
+	invoke Invoker
 }
 
 type watcher interface {
@@ -28,9 +34,9 @@ type watcher interface {
 }
 
 type jobExecutor interface {
-	processJob(Job)
+	processJob(context.Context, Job)
 	initializeResult(Job, time.Time) report.Result
-	processResponse(report.Result, *http.Response, time.Time, time.Time, Job, int)
+	processResponse(report.Result, *http.Response, time.Time, time.Time, Job, int) report.Result
 }
 
 type requestProcessor interface {
@@ -47,14 +53,29 @@ type Job struct {
 
 type WorkerFactory func(id int, jobs <-chan Job, results chan<- report.Result, client *http.Client, cfg config.Config) Worker
 
-// Create a new worker instance
-func NewWorker(id int, jobs <-chan Job, results chan<- report.Result, client *http.Client, cfg config.Config) *Worker {
+// Invoker sends a single attempt of a job over something other than HTTP,
+// such as a gRPC channel, and returns its result
+type Invoker func(ctx context.Context, job Job) report.Result
+
+// Create a new worker instance. With sessions enabled the worker gets its own
+// cookie jar on top of the shared transport.
+func NewWorker(id int, jobs <-chan Job, results chan<- report.Result, httpClient *http.Client, cfg config.Config) *Worker {
+	if cfg.Sessions && httpClient != nil {
+		session, err := client.NewSessionClient(httpClient, cfg.ParsedCookies)
+		if err != nil {
+			cfg.Logger.Error("worker %d: Failed to create cookie jar: %v", id, err)
+		} else {
+			httpClient = session
+		}
+	}
+
 	return &Worker{
 		ID:      id,
 		Jobs:    jobs,
 		Results: results,
-		Client:  client,
+		Client:  httpClient,
 		Config:  cfg,
+		retry:   newRetryPolicy(cfg),
 	}
 }
 
@@ -71,18 +92,65 @@ func (w *Worker) watch(ctx context.Context, wg *sync.WaitGroup) {
 			if !ok {
 				return
 			}
-			w.processJob(job)
+			w.processJob(ctx, job)
+		}
+	}
+}
+
+// Process a single job, retrying it according to the retry policy
+func (w *Worker) processJob(ctx context.Context, job Job) {
+	var first report.Result
+	var reasons []string
+	resends := 0
+
+	for attempt := 1; ; attempt++ {
+		result, retryAfter := w.send(ctx, job)
+		result.Attempts = attempt
+		resends += result.AuthResends
+		result.AuthResends = resends
+		if attempt == 1 {
+			first = result
+		} else {
+			result.FirstResultCode = first.ResultCode
+			result.FirstErrorClass = first.ErrorClass
+			result.RetryReasons = reasons
+		}
+
+		retry, reason := w.retry.shouldRetry(result, attempt)
+		if !retry {
+			w.Results <- result
+			return
+		}
+
+		delay := w.retry.delay(attempt, retryAfter)
+		w.Config.Logger.Debug("worker %d: Retrying %s after %s (attempt %d, reason %s)", w.ID, job.Host, delay, attempt, reason)
+		reasons = append(reasons, reason)
+
+		select {
+		case <-ctx.Done():
+			w.Results <- result
+			return
+		case <-time.After(delay):
 		}
 	}
 }
 
-// Process a single job
-func (w *Worker) processJob(job Job) {
+// send makes a single attempt of the job, with the invoker if the worker has
+// one and over HTTP otherwise
+func (w *Worker) send(ctx context.Context, job Job) (report.Result, time.Duration) {
+	if w.invoke != nil {
+		return w.invoke(ctx, job), 0
+	}
+	return w.attempt(job)
+}
+
+// attempt sends a single request for the job. It also returns how long the
+// server asked the client to wait with Retry-After, if at all.
+func (w *Worker) attempt(job Job) (report.Result, time.Duration) {
 	w.Config.Logger.Debug("worker %d: Starting job for %s with method %s", w.ID, job.Host, job.Method)
 	req, err := w.createRequest(job)
 	if err != nil {
-		w.handleRequestError(job, err)
-		return
+		return w.handleRequestError(job, err), 0
 	}
 
 	reqSize, err := util.CalculateRawRequestSize(req)
@@ -92,29 +160,110 @@ func (w *Worker) processJob(job Job) {
 
 	w.setHeaders(req)
 
+	// sign after the headers are set so they can be covered by the signature;
+	// each attempt is signed afresh with the current time
+	if w.signer != nil {
+		if err := w.signer.Sign(req, []byte(job.Body), time.Now()); err != nil {
+			w.Config.Logger.Error("worker %d: Failed to sign request for %s: %v", w.ID, job.Host, err)
+			return w.handleRequestError(job, err), 0
+		}
+	}
+
+	trace := &requestTrace{}
+	conns := &client.ConnStats{}
+	authTiming := &auth.Timing{}
+	ctx := trace.withTrace(req.Context())
+	ctx = client.WithConnStats(ctx, conns)
+	ctx = auth.WithTiming(ctx, authTiming)
+	req = req.WithContext(ctx)
+
 	start := time.Now()
 	result := w.initializeResult(job, start)
 
 	resp, err := w.Client.Do(req)
+	result.AuthTime = authTiming.Duration()
+	result.AuthResends = authTiming.Resent()
+	result.HandshakeTime = trace.handshakeTime()
+	result.Connection = trace.connection()
+	result.ConnectionsOpened = conns.Opened()
+	result.ProxyTime = conns.ProxyTime()
+	result.SourceIP = trace.sourceIP()
+	// when spreading, the address picked for a new connection is the backend
+	// even if the connection itself goes to a proxy
+	result.RemoteIP = trace.remoteIP()
+	if dialed := conns.DialedIP(); dialed != "" && (w.Config.SpreadAddrs || result.RemoteIP == "") {
+		result.RemoteIP = dialed
+	}
 	if err != nil {
 		end := time.Now()
-		w.handleClientError(job, result, resp, err, start, end)
-		return
+		return excludeAuthTime(w.handleClientError(job, result, resp, err, start, end)), 0
 	}
 	end := time.Now()
 
 	defer resp.Body.Close()
-	w.processResponse(result, resp, start, end, job, reqSize)
+	result.Protocol = resp.Proto
+	retryAfter, _ := parseRetryAfter(resp.Header.Get("Retry-After"), end)
+	return excludeAuthTime(w.processResponse(result, resp, start, end, job, reqSize)), retryAfter
+}
+
+// excludeAuthTime removes the time spent on authentication from the
+// request's latency; it is reported separately
+func excludeAuthTime(result report.Result) report.Result {
+	result.ElapsedTime -= result.AuthTime
+	return result
 }
 
-// todo: create a comment for this function
+// Work runs the jobs through a worker pool and sends the aggregated report
+// on reportChan once every result has been collected
 func Work(ctx context.Context, cfg config.Config, jobs []Job, reportChan chan<- report.Report, factory WorkerFactory) {
-	client, err := client.NewClient(cfg)
+	resultChan, err := Start(ctx, cfg, jobs, factory)
 	if err != nil {
 		cfg.Logger.Error("Error creating HTTP client: %v", err)
 		return
 	}
 
+	cfg.Logger.Info("Aggregating results into report")
+	report := ProcessResults(cfg, resultChan)
+
+	cfg.Logger.Info("Report aggregation complete")
+	reportChan <- report
+	close(reportChan)
+}
+
+// Start launches the worker pool and dispatches jobs at the configured RPS.
+// The returned channel receives every result and is closed once all workers
+// have exited.
+func Start(ctx context.Context, cfg config.Config, jobs []Job, factory WorkerFactory) (<-chan report.Result, error) {
+	client, err := client.NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := signing.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return dispatch(ctx, cfg, jobs, func(id int, jobChan <-chan Job, resultChan chan<- report.Result) Worker {
+		worker := factory(id, jobChan, resultChan, client, cfg)
+		worker.signer = signer
+		return worker
+	}), nil
+}
+
+// StartInvoker is Start for jobs that invoke sends instead of the HTTP
+// client. Retries and the pacing of jobs work the same way.
+func StartInvoker(ctx context.Context, cfg config.Config, jobs []Job, invoke Invoker) <-chan report.Result {
+	return dispatch(ctx, cfg, jobs, func(id int, jobChan <-chan Job, resultChan chan<- report.Result) Worker {
+		worker := NewWorker(id, jobChan, resultChan, nil, cfg)
+		worker.invoke = invoke
+		return *worker
+	})
+}
+
+// dispatch starts the workers newWorker creates and feeds them the jobs at
+// the configured RPS
+func dispatch(ctx context.Context, cfg config.Config, jobs []Job, newWorker func(int, <-chan Job, chan<- report.Result) Worker) <-chan report.Result {
 	numWorkers := cfg.RPS * 10
 	jobChan := make(chan Job, len(jobs))
 	resultChan := make(chan report.Result, len(jobs))
@@ -123,7 +272,7 @@ func Work(ctx context.Context, cfg config.Config, jobs []Job, reportChan chan<-
 
 	cfg.Logger.Info("Starting worker pool with %d workers", numWorkers)
 	for i := 0; i < numWorkers; i++ {
-		worker := factory(i, jobChan, resultChan, client, cfg)
+		worker := newWorker(i, jobChan, resultChan)
 		wg.Add(1)
 		go worker.watch(ctx, wg)
 	}
@@ -148,24 +297,31 @@ func Work(ctx context.Context, cfg config.Config, jobs []Job, reportChan chan<-
 		close(resultChan)
 	}()
 
-	cfg.Logger.Info("Aggregating results into report")
-	report := processResults(cfg, resultChan)
+	return resultChan
+}
 
-	cfg.Logger.Info("Report aggregation complete")
-	reportChan <- report
-	close(reportChan)
+// CreateJobs builds one job per request described by the config
+func CreateJobs(cfg config.Config) []Job {
+	jobs := make([]Job, cfg.Requests)
+	for i := 0; i < cfg.Requests; i++ {
+		jobs[i] = Job{ID: i, Host: cfg.URL, Method: cfg.Method, Body: cfg.Body}
+	}
+	return jobs
+}
+
+// DefaultFactory creates workers with NewWorker
+func DefaultFactory(id int, jobChan <-chan Job, resultChan chan<- report.Result, client *http.Client, cfg config.Config) Worker {
+	return *NewWorker(id, jobChan, resultChan, client, cfg)
 }
 
-// Process results from workers
-func processResults(cfg config.Config, resultChan <-chan report.Result) report.Report {
+// ProcessResults aggregates results from workers into a report
+func ProcessResults(cfg config.Config, resultChan <-chan report.Result) report.Report {
 	report := report.Report{}
 	var totalRequests, totalBytesSent, totalBytesReceived int
-	resultCodes := make(map[int]int)
 	var duration time.Duration
 
 	for result := range resultChan {
 		totalRequests++
-		resultCodes[result.ResultCode]++
 		totalBytesSent += result.BytesSent
 		totalBytesReceived += result.BytesReceived
 		duration += result.ElapsedTime
@@ -179,8 +335,12 @@ func processResults(cfg config.Config, resultChan <-chan report.Result) report.R
 			report.ErrorBreakdown.ServerErrors++
 		}
 
+		if result.ErrorClass != "" {
+			report.ErrorBreakdown.AddTransportError(result.ErrorClass, result.EndTime, result.Error)
+		}
+
 		// count all failed requests
-		if result.ResultCode >= 400 {
+		if !result.Succeeded() {
 			report.Failures++
 			cfg.Logger.Warn("Request failed with status code %d", result.ResultCode)
 		} else {
@@ -199,8 +359,25 @@ func processResults(cfg config.Config, resultChan <-chan report.Result) report.R
 	report.Throughput.TotalBytesReceived = totalBytesReceived
 	report.Throughput.BytesSentPerSecond = util.CalculateBytesPerSecond(float64(totalBytesSent), duration.Seconds())
 	report.Throughput.BytesReceivedPerSecond = util.CalculateBytesPerSecond(float64(totalBytesReceived), duration.Seconds())
-	report.ConvertResultCodes(resultCodes)
+	report.CalculateStatusCodes()
+	if cfg.MaxAttempts > 1 {
+		report.CalculateRetries()
+	}
 	report.CalculateLatencyMetrics()
+	report.CalculateProtocols()
+	report.CalculateConnections()
+	if cfg.Proxy != "" {
+		report.CalculateProxyTunnels()
+	}
+	if cfg.Auth != "" {
+		report.CalculateAuthentication()
+	}
+	if cfg.SourceAddrs != "" {
+		report.CalculateSources()
+	}
+	if cfg.SpreadAddrs {
+		report.CalculateBackends()
+	}
 
 	return report
 }
@@ -233,24 +410,23 @@ func (w *Worker) initializeResult(job Job, start time.Time) report.Result {
 }
 
 // Process the HTTP response
-func (w *Worker) processResponse(result report.Result, resp *http.Response, start time.Time, end time.Time, job Job, bytesSent int) {
+func (w *Worker) processResponse(result report.Result, resp *http.Response, start time.Time, end time.Time, job Job, bytesSent int) report.Result {
 	if resp == nil {
 		w.Config.Logger.Error("worker %d: No response received for %s", w.ID, job.Host)
 		result.Error = fmt.Errorf("no response received")
 		result.EndTime = end
 		result.ElapsedTime = result.EndTime.Sub(start)
-		w.Results <- result
-		return
+		return result
 	}
 
 	bytesReceived, err := httputil.DumpResponse(resp, true)
 	if err != nil {
 		w.Config.Logger.Error("worker %d: Failed to dump response from %s: %v", w.ID, job.Host, err)
 		result.Error = err
+		result.ErrorClass = report.ErrorClassBodyRead
 		result.EndTime = time.Now()
 		result.ElapsedTime = result.EndTime.Sub(start)
-		w.Results <- result
-		return
+		return result
 	}
 
 	result.BytesReceived = len(bytesReceived)
@@ -262,5 +438,5 @@ func (w *Worker) processResponse(result report.Result, resp *http.Response, star
 	result.ResultCode = resp.StatusCode
 
 	w.Config.Logger.Debug("worker %d: Completed job for %s with status %d in %s", w.ID, job.Host, result.ResultCode, result.ElapsedTime)
-	w.Results <- result
+	return result
 }
@@ -1,13 +1,25 @@
 package worker
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/rnemeth90/yahba/internal/auth"
 	"github.com/rnemeth90/yahba/internal/config"
 	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/rnemeth90/yahba/internal/report"
 	"github.com/rnemeth90/yahba/internal/util"
 	"github.com/stretchr/testify/assert"
 )
@@ -121,3 +133,213 @@ func TestInitializeResult(t *testing.T) {
 	assert.Equal(t, result.StartTime, ti)
 	assert.NoError(t, result.Error)
 }
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"nil", nil, ""},
+		{"dns", &url.Error{Op: "Get", URL: "http://nope.invalid", Err: &net.DNSError{Err: "no such host", Name: "nope.invalid"}}, report.ErrorClassDNS},
+		{"refused", &url.Error{Op: "Get", Err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}}, report.ErrorClassConnRefused},
+		{"reset", &url.Error{Op: "Get", Err: &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}}, report.ErrorClassConnReset},
+		{"proxy", &url.Error{Op: "Get", Err: &net.OpError{Op: "proxyconnect", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}}, report.ErrorClassProxy},
+		{"eof", &url.Error{Op: "Get", Err: io.EOF}, report.ErrorClassEOF},
+		{"canceled", &url.Error{Op: "Get", Err: context.Canceled}, report.ErrorClassContextCanceled},
+		{"timeout", &url.Error{Op: "Get", Err: context.DeadlineExceeded}, report.ErrorClassTimeout},
+		{"tls", &url.Error{Op: "Get", Err: tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}}, report.ErrorClassTLSHandshake},
+		{"token", &url.Error{Op: "Get", Err: fmt.Errorf("%w: %w", auth.ErrTokenRequest, context.DeadlineExceeded)}, report.ErrorClassAuth},
+		{"other", errors.New("something else"), report.ErrorClassOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ClassifyError(tt.err))
+		})
+	}
+}
+
+func TestHandleClientErrorTimeout(t *testing.T) {
+	w := NewWorker(1, nil, nil, nil, config.Config{Logger: logger.New("error", "stdout", false)})
+	err := &url.Error{Op: "Get", URL: "http://localhost", Err: context.DeadlineExceeded}
+
+	now := time.Now()
+	result := w.handleClientError(Job{Host: "http://localhost"}, report.Result{}, nil, err, now, now)
+	assert.True(t, result.Timeout)
+	assert.Equal(t, 0, result.ResultCode)
+	assert.Equal(t, report.ErrorClassTimeout, result.ErrorClass)
+}
+
+func TestClassifyErrorConnectionRefused(t *testing.T) {
+	server := mockServer()
+	addr := server.URL
+	server.Close()
+
+	_, err := http.Get(addr)
+	assert.Error(t, err)
+	assert.Equal(t, report.ErrorClassConnRefused, ClassifyError(err))
+}
+
+func TestSessionsAreIsolated(t *testing.T) {
+	// log in on the first request, then expect the session cookie back
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("session"); err != nil {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "logged-in"})
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	cfg := config.Config{Sessions: true, Logger: logger.New("error", "stdout", false)}
+	shared := &http.Client{}
+	alice := NewWorker(1, nil, nil, shared, cfg)
+	bob := NewWorker(2, nil, nil, shared, cfg)
+
+	job := Job{Host: ts.URL, Method: "GET"}
+	first, _ := alice.attempt(job)
+	second, _ := alice.attempt(job)
+	other, _ := bob.attempt(job)
+
+	assert.Equal(t, http.StatusUnauthorized, first.ResultCode)
+	assert.Equal(t, http.StatusOK, second.ResultCode)
+	assert.Equal(t, http.StatusUnauthorized, other.ResultCode)
+}
+
+func TestAuthTimeExcludedFromLatency(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte(`{"access_token":"abc","expires_in":300}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer ts.Close()
+
+	cfg := config.Config{
+		URL:            ts.URL + "/api",
+		Method:         "GET",
+		Requests:       1,
+		RPS:            1,
+		Timeout:        5,
+		Auth:           "oauth2",
+		OAuth2TokenURL: ts.URL + "/token",
+		OAuth2ClientID: "yahba",
+		Logger:         logger.New("error", "stdout", false),
+	}
+
+	results, err := Start(context.Background(), cfg, CreateJobs(cfg), DefaultFactory)
+	assert.NoError(t, err)
+	r := ProcessResults(cfg, results)
+
+	result := r.Results[0]
+	assert.Equal(t, http.StatusOK, result.ResultCode)
+	assert.GreaterOrEqual(t, result.AuthTime, 200*time.Millisecond)
+	assert.Less(t, result.ElapsedTime, 200*time.Millisecond)
+	if assert.NotNil(t, r.Authentication) {
+		assert.Equal(t, 1, r.Authentication.DelayedRequests)
+	}
+}
+
+func TestAuthFailureExcludedFromLatency(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			time.Sleep(200 * time.Millisecond)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	cfg := config.Config{
+		URL:            ts.URL + "/api",
+		Method:         "GET",
+		Requests:       1,
+		RPS:            1,
+		Timeout:        5,
+		Auth:           "oauth2",
+		OAuth2TokenURL: ts.URL + "/token",
+		OAuth2ClientID: "yahba",
+		Logger:         logger.New("error", "stdout", false),
+	}
+
+	results, err := Start(context.Background(), cfg, CreateJobs(cfg), DefaultFactory)
+	assert.NoError(t, err)
+	r := ProcessResults(cfg, results)
+
+	// the failed token fetch is reported as auth, not as a slow target
+	result := r.Results[0]
+	assert.Equal(t, report.ErrorClassAuth, result.ErrorClass)
+	assert.GreaterOrEqual(t, result.AuthTime, 200*time.Millisecond)
+	assert.Less(t, result.ElapsedTime, 200*time.Millisecond)
+}
+
+func TestSignedRequests(t *testing.T) {
+	var mu sync.Mutex
+	signatures := make(map[string]bool)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"id":1}` || r.Header.Get("X-Timestamp") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		signatures[r.Header.Get("X-Signature")] = true
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	t.Setenv("YAHBA_SECRET", "s3cret")
+	cfg := config.Config{
+		URL:           ts.URL + "/orders",
+		Method:        "POST",
+		Body:          `{"id":1}`,
+		Requests:      2,
+		RPS:           1,
+		Timeout:       5,
+		Sign:          "hmac-sha256",
+		SignSecretEnv: "YAHBA_SECRET",
+		Logger:        logger.New("error", "stdout", false),
+	}
+
+	results, err := Start(context.Background(), cfg, CreateJobs(cfg), DefaultFactory)
+	assert.NoError(t, err)
+	r := ProcessResults(cfg, results)
+
+	assert.Equal(t, 2, r.StatusCodes["200"].Count)
+	// the requests are a second apart, so each carries its own signature
+	assert.Len(t, signatures, 2)
+}
+
+func TestStartInvoker(t *testing.T) {
+	var calls sync.Map
+	invoke := func(ctx context.Context, job Job) report.Result {
+		result := report.Result{ResultCode: http.StatusOK, Method: job.Method, TargetURL: job.Host}
+		// the first attempt of every job fails
+		if _, retried := calls.LoadOrStore(job.ID, true); !retried {
+			result.ResultCode = 0
+			result.ErrorClass = report.ErrorClassConnRefused
+		}
+		return result
+	}
+
+	cfg := config.Config{
+		Requests:    3,
+		RPS:         10,
+		MaxAttempts: 2,
+		RetryErrors: report.ErrorClassConnRefused,
+		Logger:      logger.New("error", "stdout", false),
+	}
+
+	r := ProcessResults(cfg, StartInvoker(context.Background(), cfg, CreateJobs(cfg), invoke))
+
+	assert.Equal(t, 3, r.Successes)
+	for _, result := range r.Results {
+		assert.Equal(t, 2, result.Attempts)
+		assert.Equal(t, report.ErrorClassConnRefused, result.FirstErrorClass)
+	}
+}
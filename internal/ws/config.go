@@ -0,0 +1,56 @@
+package ws
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/rnemeth90/yahba/internal/logger"
+)
+
+// DefaultMessage is sent when neither Message nor MessageFile is set
+const DefaultMessage = `{"conn":{{.Conn}},"seq":{{.Seq}}}`
+
+// Config describes a WebSocket load test. Connections are opened evenly over
+// Ramp and each sends Rate messages per second until Ramp+Duration has
+// passed.
+type Config struct {
+	URL         string
+	Connections int
+	Ramp        time.Duration
+	Duration    time.Duration
+	// Rate is messages per second per connection. Zero only holds the
+	// connections open.
+	Rate float64
+	// Message is a text/template executed for every message. MessageFile
+	// holds one such template per line, sent in order and repeated.
+	Message     string
+	MessageFile string
+	// Headers are sent with the upgrade request (Key1:Value1,Key2:Value2)
+	Headers string
+	// Timeout bounds the handshake and how long a message waits for its reply
+	Timeout  time.Duration
+	Insecure bool
+	Logger   *logger.Logger
+}
+
+// Validate checks the configuration
+func (c *Config) Validate() error {
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "ws" && u.Scheme != "wss") || u.Host == "" {
+		return ErrInvalidURL
+	}
+
+	if c.Connections <= 0 {
+		return ErrInvalidConnections
+	}
+
+	if c.Duration <= 0 || c.Timeout <= 0 || c.Ramp < 0 || c.Rate < 0 {
+		return ErrInvalidDuration
+	}
+
+	if c.Message != "" && c.MessageFile != "" {
+		return ErrConflictingMessages
+	}
+
+	return nil
+}
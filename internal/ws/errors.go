@@ -0,0 +1,11 @@
+package ws
+
+import "errors"
+
+var (
+	ErrInvalidURL          = errors.New("invalid WebSocket URL. The scheme must be ws or wss")
+	ErrInvalidConnections  = errors.New("the number of connections must be greater than zero")
+	ErrInvalidDuration     = errors.New("the test duration and timeout must be greater than zero, and the ramp and rate must not be negative")
+	ErrConflictingMessages = errors.New("use either --message or --message-file, not both")
+	ErrEmptyScript         = errors.New("message file contains no messages")
+)
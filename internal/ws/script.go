@@ -0,0 +1,74 @@
+package ws
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// messageData is available to message templates
+type messageData struct {
+	// Conn is the connection number, starting at 0
+	Conn int
+	// Seq is the message number on the connection, starting at 0
+	Seq  int
+	Time time.Time
+}
+
+// script renders the messages each connection sends
+type script struct {
+	templates []*template.Template
+}
+
+// loadScript parses the configured message or message file
+func loadScript(cfg Config) (*script, error) {
+	var lines []string
+	switch {
+	case cfg.MessageFile != "":
+		f, err := os.Open(cfg.MessageFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		if len(lines) == 0 {
+			return nil, ErrEmptyScript
+		}
+	case cfg.Message != "":
+		lines = []string{cfg.Message}
+	default:
+		lines = []string{DefaultMessage}
+	}
+
+	s := &script{}
+	for _, line := range lines {
+		tmpl, err := template.New("message").Parse(line)
+		if err != nil {
+			return nil, err
+		}
+		s.templates = append(s.templates, tmpl)
+	}
+	return s, nil
+}
+
+// render returns message seq of connection conn
+func (s *script) render(conn, seq int) ([]byte, error) {
+	var buf bytes.Buffer
+	tmpl := s.templates[seq%len(s.templates)]
+	if err := tmpl.Execute(&buf, messageData{Conn: conn, Seq: seq, Time: time.Now()}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
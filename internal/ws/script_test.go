@@ -0,0 +1,37 @@
+package ws
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScript(t *testing.T) {
+	s, err := loadScript(Config{})
+	assert.NoError(t, err)
+	message, err := s.render(2, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"conn":2,"seq":7}`, string(message))
+
+	path := filepath.Join(t.TempDir(), "script.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("subscribe {{.Conn}}\n\nping {{.Seq}}\n"), 0600))
+	s, err = loadScript(Config{MessageFile: path})
+	assert.NoError(t, err)
+
+	var sent []string
+	for seq := 0; seq < 3; seq++ {
+		message, err := s.render(1, seq)
+		assert.NoError(t, err)
+		sent = append(sent, string(message))
+	}
+	assert.Equal(t, []string{"subscribe 1", "ping 1", "subscribe 1"}, sent)
+
+	assert.NoError(t, os.WriteFile(path, []byte("\n\n"), 0600))
+	_, err = loadScript(Config{MessageFile: path})
+	assert.ErrorIs(t, err, ErrEmptyScript)
+
+	_, err = loadScript(Config{Message: "{{.Missing"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,374 @@
+// Package ws load tests WebSocket services. Each connection sends messages
+// at a fixed rate and matches every message it receives to the message it
+// echoes, or else to the oldest message still waiting for a reply, which
+// suits echo and request/response protocols.
+package ws
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rnemeth90/yahba/internal/report"
+	"github.com/rnemeth90/yahba/internal/util"
+	"github.com/rnemeth90/yahba/internal/worker"
+)
+
+// closeTimeout bounds the closing handshake at the end of the test
+const closeTimeout = time.Second
+
+// closeCodeNames names the close codes a disconnect is commonly reported with
+var closeCodeNames = map[int]string{
+	websocket.CloseNormalClosure:     "normal closure",
+	websocket.CloseGoingAway:         "going away",
+	websocket.CloseProtocolError:     "protocol error",
+	websocket.CloseAbnormalClosure:   "abnormal closure",
+	websocket.ClosePolicyViolation:   "policy violation",
+	websocket.CloseMessageTooBig:     "message too big",
+	websocket.CloseInternalServerErr: "internal error",
+	websocket.CloseServiceRestart:    "service restart",
+	websocket.CloseTryAgainLater:     "try again later",
+}
+
+// Run opens the connections, exchanges messages until the test ends and
+// returns the report
+func Run(ctx context.Context, cfg Config) (report.Report, error) {
+	if err := cfg.Validate(); err != nil {
+		return report.Report{}, err
+	}
+
+	script, err := loadScript(cfg)
+	if err != nil {
+		return report.Report{}, err
+	}
+
+	headers := http.Header{}
+	if cfg.Headers != "" {
+		parsed, err := util.ParseHeaders(cfg.Headers)
+		if err != nil {
+			return report.Report{}, err
+		}
+		for _, h := range parsed {
+			headers.Add(h.Key, h.Value)
+		}
+	}
+
+	r := &runner{
+		cfg:     cfg,
+		script:  script,
+		headers: headers,
+		dialer: &websocket.Dialer{
+			Proxy:            http.ProxyFromEnvironment,
+			HandshakeTimeout: cfg.Timeout,
+			TLSClientConfig:  &tls.Config{InsecureSkipVerify: cfg.Insecure},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Ramp+cfg.Duration)
+	defer cancel()
+
+	results := make(chan report.Result, 1024)
+	conns := make([]report.WebSocketConn, cfg.Connections)
+	wg := &sync.WaitGroup{}
+	start := time.Now()
+
+	cfg.Logger.Info("Opening %d WebSocket connections over %s", cfg.Connections, cfg.Ramp)
+	for i := 0; i < cfg.Connections; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			delay := cfg.Ramp * time.Duration(id) / time.Duration(cfg.Connections)
+			select {
+			case <-ctx.Done():
+				conns[id] = report.WebSocketConn{ConnectError: report.ErrorClassContextCanceled}
+				return
+			case <-time.After(delay):
+			}
+			conns[id] = r.connect(ctx, id, results)
+		}(i)
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var all []report.Result
+	for result := range results {
+		all = append(all, result)
+	}
+
+	return report.NewWebSocketReport(cfg.URL, start, time.Now(), all, conns), nil
+}
+
+type runner struct {
+	cfg     Config
+	script  *script
+	headers http.Header
+	dialer  *websocket.Dialer
+}
+
+// connect opens connection id and exchanges messages on it until ctx is done
+func (r *runner) connect(ctx context.Context, id int, results chan<- report.Result) report.WebSocketConn {
+	start := time.Now()
+	conn, resp, err := r.dialer.DialContext(ctx, r.cfg.URL, r.headers)
+	if err != nil {
+		r.cfg.Logger.Debug("connection %d: Failed to connect: %v", id, err)
+		if resp != nil {
+			return report.WebSocketConn{ConnectError: strconv.Itoa(resp.StatusCode)}
+		}
+		return report.WebSocketConn{ConnectError: worker.ClassifyError(err)}
+	}
+	defer conn.Close()
+
+	s := &session{
+		runner:  r,
+		id:      id,
+		conn:    conn,
+		results: results,
+		replied: make(chan struct{}, 1),
+		late:    make(map[string]int),
+	}
+	stats := report.WebSocketConn{ConnectTime: time.Since(start)}
+	stats.Disconnect = s.run(ctx)
+	stats.Received = s.receivedCount()
+	r.cfg.Logger.Debug("connection %d: Disconnected (%s)", id, stats.Disconnect)
+	return stats
+}
+
+// pendingMessage is a message waiting for its reply
+type pendingMessage struct {
+	sent    time.Time
+	payload string
+}
+
+// session is a single established connection
+type session struct {
+	*runner
+	id      int
+	conn    *websocket.Conn
+	results chan<- report.Result
+	// replied is signalled whenever a reply arrives
+	replied chan struct{}
+
+	mu       sync.Mutex
+	pending  []pendingMessage
+	received int
+	// late counts timed out messages by payload, so their echoes are skipped
+	// instead of being matched to later messages
+	late map[string]int
+}
+
+// run sends messages at the configured rate until ctx is done or the
+// connection ends, and returns why it ended. The reader has always stopped
+// by the time it returns.
+func (s *session) run(ctx context.Context) string {
+	readErr := make(chan error, 1)
+	go s.read(readErr)
+
+	var tick <-chan time.Time
+	if s.cfg.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / s.cfg.Rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for seq := 0; ; seq++ {
+		select {
+		case <-ctx.Done():
+			return s.close(readErr)
+		case err := <-readErr:
+			return s.disconnected(err)
+		case <-tick:
+		}
+
+		s.expire(time.Now())
+		message, err := s.script.render(s.id, seq)
+		if err != nil {
+			s.cfg.Logger.Error("connection %d: Failed to render message %d: %v", s.id, seq, err)
+			s.conn.Close()
+			return s.disconnected(<-readErr)
+		}
+
+		s.mu.Lock()
+		s.pending = append(s.pending, pendingMessage{sent: time.Now(), payload: string(message)})
+		s.mu.Unlock()
+
+		if err := s.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			s.conn.Close()
+			<-readErr
+			return s.disconnected(err)
+		}
+	}
+}
+
+// read receives messages until the connection fails or closes, matching
+// each to the message waiting for a reply that it echoes, or else to the
+// oldest one
+func (s *session) read(readErr chan<- error) {
+	for {
+		_, message, err := s.conn.ReadMessage()
+		if err != nil {
+			readErr <- err
+			return
+		}
+		now := time.Now()
+
+		s.mu.Lock()
+		s.received++
+		p, ok := s.match(string(message))
+		s.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		result := s.result(p, now)
+		result.BytesReceived = len(message)
+		s.results <- result
+
+		select {
+		case s.replied <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// match removes and returns the message a reply answers. The echo of a
+// message that already timed out answers nothing, and neither does a
+// message the server pushed on its own. The caller holds s.mu.
+func (s *session) match(reply string) (pendingMessage, bool) {
+	if s.late[reply] > 0 {
+		s.late[reply]--
+		if s.late[reply] == 0 {
+			delete(s.late, reply)
+		}
+		return pendingMessage{}, false
+	}
+	if len(s.pending) == 0 {
+		return pendingMessage{}, false
+	}
+
+	i := slices.IndexFunc(s.pending, func(p pendingMessage) bool { return p.payload == reply })
+	if i < 0 {
+		i = 0
+	}
+	p := s.pending[i]
+	s.pending = slices.Delete(s.pending, i, i+1)
+	return p, true
+}
+
+// close waits for the outstanding replies, then closes the connection with
+// a normal closure
+func (s *session) close(readErr <-chan error) string {
+	deadline := time.NewTimer(s.cfg.Timeout)
+	defer deadline.Stop()
+
+	for s.outstanding() > 0 {
+		select {
+		case err := <-readErr:
+			return s.disconnected(err)
+		case <-deadline.C:
+			s.fail(time.Now(), report.ErrorClassTimeout, errors.New("no reply before the test ended"))
+		case <-s.replied:
+		}
+	}
+
+	s.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(closeTimeout))
+
+	// wait for the server to acknowledge the close, and for the reader to
+	// stop before the results channel can be closed
+	select {
+	case <-readErr:
+	case <-time.After(closeTimeout):
+		s.conn.Close()
+		<-readErr
+	}
+	return report.DisconnectClientClosed
+}
+
+// disconnected fails the messages still waiting for a reply and returns the
+// reason the connection ended
+func (s *session) disconnected(err error) string {
+	s.fail(time.Now(), report.ErrorClassConnClosed, err)
+
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		if name, ok := closeCodeNames[closeErr.Code]; ok {
+			return fmt.Sprintf("close %d (%s)", closeErr.Code, name)
+		}
+		return fmt.Sprintf("close %d", closeErr.Code)
+	}
+	return worker.ClassifyError(err)
+}
+
+// expire fails the messages that have waited longer than the timeout
+func (s *session) expire(now time.Time) {
+	s.mu.Lock()
+	var expired []pendingMessage
+	for len(s.pending) > 0 && now.Sub(s.pending[0].sent) > s.cfg.Timeout {
+		expired = append(expired, s.pending[0])
+		s.pending = s.pending[1:]
+	}
+	for _, p := range expired {
+		s.late[p.payload]++
+	}
+	s.mu.Unlock()
+
+	for _, p := range expired {
+		s.results <- s.failure(p, now, report.ErrorClassTimeout, errors.New("no reply within the timeout"))
+	}
+}
+
+// fail fails every message still waiting for a reply
+func (s *session) fail(now time.Time, class string, err error) {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	for _, p := range pending {
+		s.results <- s.failure(p, now, class, err)
+	}
+}
+
+func (s *session) outstanding() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pending)
+}
+
+func (s *session) receivedCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.received
+}
+
+// result returns the result of a message answered at end
+func (s *session) result(p pendingMessage, end time.Time) report.Result {
+	return report.Result{
+		WorkerID:    s.id,
+		StartTime:   p.sent,
+		EndTime:     end,
+		ElapsedTime: end.Sub(p.sent),
+		Method:      "WS",
+		TargetURL:   s.cfg.URL,
+		BytesSent:   len(p.payload),
+	}
+}
+
+// failure returns the result of a message that never got a reply
+func (s *session) failure(p pendingMessage, end time.Time, class string, err error) report.Result {
+	result := s.result(p, end)
+	result.Error = err
+	result.ErrorClass = class
+	return result
+}
@@ -0,0 +1,164 @@
+package ws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rnemeth90/yahba/internal/logger"
+	"github.com/rnemeth90/yahba/internal/report"
+	"github.com/rnemeth90/yahba/internal/server"
+	"github.com/stretchr/testify/assert"
+)
+
+// echoServer starts the test server and returns the WebSocket URL of its
+// echo endpoint
+func echoServer(t *testing.T) string {
+	t.Helper()
+	s := &server.Server{Config: &server.Config{}, Logger: logger.New("error", "stdout", false)}
+	handler, err := s.Handler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(handler)
+	t.Cleanup(func() {
+		ts.Close()
+		s.Close()
+	})
+	return "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws"
+}
+
+func testConfig(url string) Config {
+	return Config{
+		URL:         url,
+		Connections: 3,
+		Ramp:        100 * time.Millisecond,
+		Duration:    500 * time.Millisecond,
+		Rate:        20,
+		Timeout:     time.Second,
+		Logger:      logger.New("error", "stdout", false),
+	}
+}
+
+func TestRun(t *testing.T) {
+	r, err := Run(context.Background(), testConfig(echoServer(t)))
+	assert.NoError(t, err)
+
+	ws := r.WebSocket
+	if !assert.NotNil(t, ws) {
+		return
+	}
+	assert.Equal(t, 3, ws.Established)
+	assert.Equal(t, map[string]int{report.DisconnectClientClosed: 3}, ws.Disconnects)
+	assert.Greater(t, r.TotalRequests, 20)
+	assert.Equal(t, r.TotalRequests, r.Successes)
+	assert.Equal(t, r.TotalRequests, ws.MessagesReceived)
+	assert.NotEmpty(t, r.Latency.P95)
+	assert.Equal(t, r.Throughput.TotalBytesSent, r.Throughput.TotalBytesReceived)
+}
+
+func TestRunServerCloses(t *testing.T) {
+	r, err := Run(context.Background(), testConfig(echoServer(t)+"?close_after=2"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]int{"close 1000 (normal closure)": 3}, r.WebSocket.Disconnects)
+	assert.Equal(t, 6, r.Successes)
+}
+
+func TestRunReplyTimeout(t *testing.T) {
+	cfg := testConfig(echoServer(t) + "?delay=400ms")
+	cfg.Connections = 1
+	cfg.Ramp = 0
+	cfg.Timeout = 100 * time.Millisecond
+
+	r, err := Run(context.Background(), cfg)
+	assert.NoError(t, err)
+	assert.Zero(t, r.Successes)
+	assert.Equal(t, r.TotalRequests, r.ErrorBreakdown.TransportErrors[report.ErrorClassTimeout].Count)
+}
+
+func TestRunLateReply(t *testing.T) {
+	// echoes every message at once, except the second, which is echoed after
+	// the client gave up on it
+	upgrader := websocket.Upgrader{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var mu sync.Mutex
+		write := func(typ int, message []byte) {
+			mu.Lock()
+			defer mu.Unlock()
+			conn.WriteMessage(typ, message)
+		}
+		for i := 0; ; i++ {
+			typ, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if i == 1 {
+				time.AfterFunc(300*time.Millisecond, func() { write(typ, message) })
+				continue
+			}
+			write(typ, message)
+		}
+	}))
+	defer ts.Close()
+
+	cfg := testConfig("ws" + strings.TrimPrefix(ts.URL, "http"))
+	cfg.Connections = 1
+	cfg.Ramp = 0
+	cfg.Timeout = 100 * time.Millisecond
+
+	r, err := Run(context.Background(), cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, r.Failures)
+	assert.Equal(t, 1, r.ErrorBreakdown.TransportErrors[report.ErrorClassTimeout].Count)
+	assert.Equal(t, r.TotalRequests-1, r.Successes)
+	// every other reply is matched to its own message, which was answered
+	// well before the next one was sent
+	for _, result := range r.Results {
+		if result.Error == nil {
+			assert.Less(t, result.ElapsedTime, 25*time.Millisecond)
+		}
+	}
+}
+
+func TestRunConnectErrors(t *testing.T) {
+	// /test answers the upgrade request with a plain 200
+	url := strings.TrimSuffix(echoServer(t), "/ws") + "/test"
+	r, err := Run(context.Background(), testConfig(url))
+	assert.NoError(t, err)
+
+	assert.Zero(t, r.WebSocket.Established)
+	assert.Equal(t, map[string]int{"200": 3}, r.WebSocket.ConnectErrors)
+	assert.Zero(t, r.TotalRequests)
+}
+
+func TestValidate(t *testing.T) {
+	valid := testConfig("ws://localhost:8081/ws")
+	assert.NoError(t, valid.Validate())
+
+	for _, tc := range []struct {
+		mutate   func(*Config)
+		expected error
+	}{
+		{func(c *Config) { c.URL = "http://localhost:8081/ws" }, ErrInvalidURL},
+		{func(c *Config) { c.Connections = 0 }, ErrInvalidConnections},
+		{func(c *Config) { c.Duration = 0 }, ErrInvalidDuration},
+		{func(c *Config) { c.Rate = -1 }, ErrInvalidDuration},
+		{func(c *Config) { c.Message = "hi"; c.MessageFile = "script.txt" }, ErrConflictingMessages},
+	} {
+		cfg := valid
+		tc.mutate(&cfg)
+		assert.ErrorIs(t, cfg.Validate(), tc.expected)
+	}
+}